@@ -0,0 +1,120 @@
+package schemalex
+
+import (
+	"strings"
+
+	"github.com/shogo82148/schemalex-deploy/model"
+)
+
+// annotationPrefix is the prefix of the annotations in comments.
+// e.g. "-- schemalex:renamed-from old_name"
+const annotationPrefix = "schemalex:"
+
+// annotation is a directive for schemalex written in a comment.
+type annotation struct {
+	Name string
+	Args []string
+}
+
+// parseAnnotation parses the comment as an annotation.
+func parseAnnotation(comment string) (annotation, bool) {
+	switch {
+	case strings.HasPrefix(comment, "--"):
+		comment = comment[2:]
+	case strings.HasPrefix(comment, "#"):
+		comment = comment[1:]
+	case strings.HasPrefix(comment, "/*"):
+		comment = strings.TrimSuffix(comment[2:], "*/")
+	}
+	comment = strings.TrimSpace(comment)
+	if !strings.HasPrefix(comment, annotationPrefix) {
+		return annotation{}, false
+	}
+	fields := strings.Fields(comment[len(annotationPrefix):])
+	if len(fields) == 0 {
+		return annotation{}, false
+	}
+	return annotation{
+		Name: fields[0],
+		Args: fields[1:],
+	}, true
+}
+
+// annotationsBefore returns the annotations in the comments just before the idx-th token.
+// The comments that end the line of a comma belong to the preceding field, so they are not included.
+func (pctx *parseCtx) annotationsBefore(idx int) []annotation {
+	var ret []annotation
+	for i := idx - 1; i >= 0 && i < len(pctx.lexsrc); i-- {
+		t := pctx.lexsrc[i]
+		if t.Type == SPACE {
+			continue
+		}
+		if t.Type != COMMENT_IDENT {
+			break
+		}
+		if pctx.followsComma(i) && pctx.lexsrc[idx].Line != t.Line {
+			break
+		}
+		if a, ok := parseAnnotation(t.Value); ok {
+			ret = append(ret, a)
+		}
+	}
+	return ret
+}
+
+// annotationsAfter returns the annotations in the comments that follow the idx-th token and end the line,
+// e.g. "a INT, -- schemalex:renamed-from old_a".
+// If another token follows the comments on the same line, the comments belong to it and nil is returned.
+func (pctx *parseCtx) annotationsAfter(idx int) []annotation {
+	if idx < 0 || idx >= len(pctx.lexsrc) {
+		return nil
+	}
+	line := pctx.lexsrc[idx].Line
+	var ret []annotation
+	for i := idx + 1; i < len(pctx.lexsrc); i++ {
+		t := pctx.lexsrc[i]
+		if t.Line != line {
+			break
+		}
+		if t.Type == SPACE {
+			continue
+		}
+		if t.Type != COMMENT_IDENT {
+			return nil
+		}
+		if a, ok := parseAnnotation(t.Value); ok {
+			ret = append(ret, a)
+		}
+	}
+	return ret
+}
+
+// followsComma reports whether the idx-th token follows a comma on the same line.
+func (pctx *parseCtx) followsComma(idx int) bool {
+	line := pctx.lexsrc[idx].Line
+	for i := idx - 1; i >= 0; i-- {
+		switch t := pctx.lexsrc[i]; t.Type {
+		case SPACE, COMMENT_IDENT:
+			continue
+		case COMMA:
+			return t.Line == line
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// renamedFrom returns the old name written in the "renamed-from" annotation.
+func renamedFrom(annotations []annotation) model.MaybeIdent {
+	for _, a := range annotations {
+		if a.Name == "renamed-from" && len(a.Args) > 0 {
+			name := a.Args[0]
+			if len(name) >= 2 && name[0] == '`' && name[len(name)-1] == '`' {
+				name = strings.ReplaceAll(name[1:len(name)-1], "``", "`")
+			}
+			return model.MaybeIdent{Ident: model.Ident(name), Valid: true}
+		}
+	}
+	return model.MaybeIdent{}
+}
@@ -8,7 +8,10 @@ import (
 	"os/user"
 	"runtime"
 	"strconv"
+	"strings"
 
+	"github.com/shogo82148/schemalex-deploy/deploy"
+	"github.com/shogo82148/schemalex-deploy/diff"
 	"github.com/shogo82148/schemalex-deploy/mycnf"
 )
 
@@ -20,6 +23,24 @@ const (
 	ExecModeDeploy ExecMode = "deploy"
 	// ExecModeImport import mode
 	ExecModeImport ExecMode = "import"
+	// ExecModeDumpJSON dump-json mode
+	ExecModeDumpJSON ExecMode = "dump-json"
+	// ExecModeRollback rollback mode
+	ExecModeRollback ExecMode = "rollback"
+	// ExecModeCheck check mode
+	ExecModeCheck ExecMode = "check"
+	// ExecModeDiff diff mode
+	ExecModeDiff ExecMode = "diff"
+)
+
+// OutputFormat is the format of the plan.
+type OutputFormat string
+
+const (
+	// OutputText writes the statements to stderr.
+	OutputText OutputFormat = "text"
+	// OutputJSON writes the plan to stdout as JSON.
+	OutputJSON OutputFormat = "json"
 )
 
 type config struct {
@@ -31,9 +52,31 @@ type config struct {
 	Database    string
 	Port        int
 	Schema      []byte
+	OldSchema   []byte
 	AutoApprove bool
 	DryRun      bool
 	Mode        ExecMode
+
+	// AllowDestructive allows the destructive changes on all tables.
+	AllowDestructive bool
+	// AllowDestructiveTables is the list of the tables whose destructive changes are allowed.
+	AllowDestructiveTables []string
+
+	// OnlineDDL appends the ALGORITHM and LOCK clauses predicted for the server to each ALTER TABLE.
+	OnlineDDL bool
+
+	// IgnoreTables is the list of the patterns of the tables to leave untouched.
+	IgnoreTables []string
+	// IgnoreColumns is the list of the patterns of the columns to leave untouched.
+	IgnoreColumns []string
+	// IgnoreTableOptions is the list of the table options to leave untouched.
+	IgnoreTableOptions []string
+
+	// Output is the format of the plan. Empty means OutputText.
+	Output OutputFormat
+
+	// RefuseDrift refuses to plan while the live schema differs from the recorded revision.
+	RefuseDrift bool
 }
 
 // for testing
@@ -48,22 +91,57 @@ func loadConfig(args []string) (*config, error) {
 	var approve bool
 	var dryRun bool
 	var runImport bool
+	var dumpJSON bool
+	var check bool
+	var refuseDrift bool
+	var allowDestructive bool
+	var allowDestructiveTables string
+	var onlineDDL bool
+	var ignoreTables, ignoreColumns, ignoreTableOptions string
+	var output string
+
+	// subcommands
+	flagArgs := args[1:]
+	var subcommand ExecMode
+	if len(flagArgs) > 0 {
+		switch mode := ExecMode(flagArgs[0]); mode {
+		case ExecModeRollback, ExecModeDiff:
+			subcommand = mode
+			flagArgs = flagArgs[1:]
+		}
+	}
 
 	flagSet := flag.NewFlagSet(args[0], flag.ExitOnError)
 
 	flagSet.Usage = func() {
 		fmt.Printf(`schemalex-deploy version %s
 
--socket           the unix domain socket path for the database
--host             the host name of the database
--port             the port number(default: 3306)
--user             username
--password         password
--database         the database name
--version          show the version
--auto-approve     skips interactive approval of plan before deploying
--dry-run          outputs the schema difference, and then exit the program
--import           imports existing table schemas from running database
+-socket                    the unix domain socket path for the database
+-host                      the host name of the database
+-port                      the port number(default: 3306)
+-user                      username
+-password                  password
+-database                  the database name
+-version                   show the version
+-auto-approve              skips interactive approval of plan before deploying
+-dry-run                   outputs the schema difference, and then exit the program
+-import                    imports existing table schemas from running database
+-dump-json                 outputs the schema file, or the running database if no file is given, as JSON
+-check                     reports the drift between the recorded revision and the running database
+-refuse-drift              refuses to deploy while the running database differs from the recorded revision
+-allow-destructive         allows destructive changes, such as dropping tables and columns
+-allow-destructive-tables  comma-separated list of the tables whose destructive changes are allowed
+-online-ddl                appends ALGORITHM and LOCK predicted for the server to each ALTER TABLE
+-ignore-tables             comma-separated list of the patterns of the tables to leave untouched
+-ignore-columns            comma-separated list of the patterns of the columns to leave untouched
+-ignore-table-options      comma-separated list of the table options to leave untouched
+-output                    the format of the plan, text(default) or json. json is written to stdout
+
+schemalex-deploy rollback [options]
+    reverts the latest deployed revision to the previous one
+
+schemalex-deploy diff [options] old.sql new.sql
+    outputs the statements to migrate old.sql to new.sql to stdout, without connecting to the database
 `, getVersion())
 	}
 
@@ -81,7 +159,17 @@ func loadConfig(args []string) (*config, error) {
 	flagSet.BoolVar(&approve, "auto-approve", false, "skips interactive approval of plan before deploying")
 	flagSet.BoolVar(&dryRun, "dry-run", false, "outputs the schema difference, and then exit the program")
 	flagSet.BoolVar(&runImport, "import", false, "imports existing table schemas from running database")
-	if err := flagSet.Parse(args[1:]); err != nil {
+	flagSet.BoolVar(&dumpJSON, "dump-json", false, "outputs the schema file, or the running database if no file is given, as JSON")
+	flagSet.BoolVar(&check, "check", false, "reports the drift between the recorded revision and the running database")
+	flagSet.BoolVar(&refuseDrift, "refuse-drift", false, "refuses to deploy while the running database differs from the recorded revision")
+	flagSet.BoolVar(&allowDestructive, "allow-destructive", false, "allows destructive changes, such as dropping tables and columns")
+	flagSet.StringVar(&allowDestructiveTables, "allow-destructive-tables", "", "comma-separated list of the tables whose destructive changes are allowed")
+	flagSet.BoolVar(&onlineDDL, "online-ddl", false, "appends ALGORITHM and LOCK predicted for the server to each ALTER TABLE")
+	flagSet.StringVar(&ignoreTables, "ignore-tables", "", "comma-separated list of the patterns of the tables to leave untouched")
+	flagSet.StringVar(&ignoreColumns, "ignore-columns", "", "comma-separated list of the patterns of the columns to leave untouched")
+	flagSet.StringVar(&ignoreTableOptions, "ignore-table-options", "", "comma-separated list of the table options to leave untouched")
+	flagSet.StringVar(&output, "output", "", "the format of the plan, text(default) or json. json is written to stdout")
+	if err := flagSet.Parse(flagArgs); err != nil {
 		return nil, err
 	}
 
@@ -92,6 +180,16 @@ func loadConfig(args []string) (*config, error) {
 
 	cfn.AutoApprove = approve
 	cfn.DryRun = dryRun
+	cfn.AllowDestructive = allowDestructive
+	cfn.OnlineDDL = onlineDDL
+	cfn.RefuseDrift = refuseDrift
+	cfn.AllowDestructiveTables = splitList(allowDestructiveTables)
+	switch OutputFormat(output) {
+	case "", OutputText, OutputJSON:
+		cfn.Output = OutputFormat(output)
+	default:
+		return nil, fmt.Errorf("unknown output format: %q", output)
+	}
 	cfn.Port = 3306
 
 	// choose execute mode
@@ -99,6 +197,15 @@ func loadConfig(args []string) (*config, error) {
 	if runImport {
 		cfn.Mode = ExecModeImport
 	}
+	if dumpJSON {
+		cfn.Mode = ExecModeDumpJSON
+	}
+	if check {
+		cfn.Mode = ExecModeCheck
+	}
+	if subcommand != "" {
+		cfn.Mode = subcommand
+	}
 
 	// load configure from files
 	cnfFile, err := loadDefault("")
@@ -127,6 +234,17 @@ func loadConfig(args []string) (*config, error) {
 			cfn.Database = v
 		}
 	}
+	if group, ok := cnfFile["schemalex-deploy"]; ok {
+		if v, ok := group["ignore-tables"]; ok {
+			cfn.IgnoreTables = splitList(v)
+		}
+		if v, ok := group["ignore-columns"]; ok {
+			cfn.IgnoreColumns = splitList(v)
+		}
+		if v, ok := group["ignore-table-options"]; ok {
+			cfn.IgnoreTableOptions = splitList(v)
+		}
+	}
 
 	// load configure from the environment values
 	// https://dev.mysql.com/doc/refman/8.0/en/environment-variables.html
@@ -174,6 +292,15 @@ func loadConfig(args []string) (*config, error) {
 	if database != "" {
 		cfn.Database = database
 	}
+	if ignoreTables != "" {
+		cfn.IgnoreTables = splitList(ignoreTables)
+	}
+	if ignoreColumns != "" {
+		cfn.IgnoreColumns = splitList(ignoreColumns)
+	}
+	if ignoreTableOptions != "" {
+		cfn.IgnoreTableOptions = splitList(ignoreTableOptions)
+	}
 
 	// deploy mode: load schema file
 	if cfn.Mode == ExecModeDeploy {
@@ -188,5 +315,75 @@ func loadConfig(args []string) (*config, error) {
 		cfn.Schema = schema
 	}
 
+	// diff mode: load the old and new schema files
+	if cfn.Mode == ExecModeDiff {
+		if flagSet.NArg() != 2 {
+			flagSet.Usage()
+			return nil, errors.New("old and new schema files are required")
+		}
+		oldSchema, err := os.ReadFile(flagSet.Arg(0))
+		if err != nil {
+			return nil, err
+		}
+		schema, err := os.ReadFile(flagSet.Arg(1))
+		if err != nil {
+			return nil, err
+		}
+		cfn.OldSchema = oldSchema
+		cfn.Schema = schema
+	}
+
+	// dump-json mode: the schema file is optional
+	if cfn.Mode == ExecModeDumpJSON && flagSet.NArg() > 0 {
+		schema, err := os.ReadFile(flagSet.Arg(0))
+		if err != nil {
+			return nil, err
+		}
+		cfn.Schema = schema
+	}
+
 	return &cfn, nil
 }
+
+// splitList splits the comma-separated list.
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// policy returns the policy to accept the destructive changes.
+func (cfn *config) policy() *deploy.Policy {
+	return &deploy.Policy{
+		AllowDestructive: cfn.AllowDestructive,
+		AllowedTables:    cfn.AllowDestructiveTables,
+	}
+}
+
+// planOptions returns the options to plan the migration.
+func (cfn *config) planOptions() []deploy.PlanOption {
+	opts := []deploy.PlanOption{
+		deploy.WithOnlineDDL(cfn.OnlineDDL),
+		deploy.WithDriftCheck(cfn.RefuseDrift),
+	}
+	if ignore := cfn.ignore(); ignore != nil {
+		opts = append(opts, deploy.WithIgnore(ignore))
+	}
+	return opts
+}
+
+// ignore returns the rules of the tables, the columns and the table options to leave untouched.
+func (cfn *config) ignore() *diff.Ignore {
+	if len(cfn.IgnoreTables) == 0 && len(cfn.IgnoreColumns) == 0 && len(cfn.IgnoreTableOptions) == 0 {
+		return nil
+	}
+	return &diff.Ignore{
+		Tables:       cfn.IgnoreTables,
+		Columns:      cfn.IgnoreColumns,
+		TableOptions: cfn.IgnoreTableOptions,
+	}
+}
@@ -142,6 +142,143 @@ func TestLoadConfig(t *testing.T) {
 				Mode:     ExecModeDeploy,
 			},
 		},
+
+		// modes
+		{
+			name: "dump the schema file as JSON",
+			args: []string{"schemalex-deploy", "-user", "shogo", "-dump-json", filepath.Join("testdata", "schema.sql")},
+			cnf:  mycnf.MyCnf{},
+			want: &config{
+				User:   "shogo",
+				Port:   3306,
+				Schema: []byte{},
+				Mode:   ExecModeDumpJSON,
+			},
+		},
+		{
+			name: "dump the running database as JSON",
+			args: []string{"schemalex-deploy", "-user", "shogo", "-dump-json"},
+			cnf:  mycnf.MyCnf{},
+			want: &config{
+				User: "shogo",
+				Port: 3306,
+				Mode: ExecModeDumpJSON,
+			},
+		},
+		{
+			name: "roll back the latest revision",
+			args: []string{"schemalex-deploy", "rollback", "-user", "shogo"},
+			cnf:  mycnf.MyCnf{},
+			want: &config{
+				User: "shogo",
+				Port: 3306,
+				Mode: ExecModeRollback,
+			},
+		},
+		{
+			name: "allow destructive changes on the tables",
+			args: []string{"schemalex-deploy", "-user", "shogo", "-allow-destructive-tables", "hoge, fuga", filepath.Join("testdata", "schema.sql")},
+			cnf:  mycnf.MyCnf{},
+			want: &config{
+				User:                   "shogo",
+				Port:                   3306,
+				Schema:                 []byte{},
+				Mode:                   ExecModeDeploy,
+				AllowDestructiveTables: []string{"hoge", "fuga"},
+			},
+		},
+		{
+			name: "online DDL",
+			args: []string{"schemalex-deploy", "-user", "shogo", "-online-ddl", filepath.Join("testdata", "schema.sql")},
+			cnf:  mycnf.MyCnf{},
+			want: &config{
+				User:      "shogo",
+				Port:      3306,
+				Schema:    []byte{},
+				Mode:      ExecModeDeploy,
+				OnlineDDL: true,
+			},
+		},
+		{
+			name: "check mode",
+			args: []string{"schemalex-deploy", "-user", "shogo", "-check"},
+			cnf:  mycnf.MyCnf{},
+			want: &config{
+				User: "shogo",
+				Port: 3306,
+				Mode: ExecModeCheck,
+			},
+		},
+		{
+			name: "refuse drift",
+			args: []string{"schemalex-deploy", "-user", "shogo", "-refuse-drift", filepath.Join("testdata", "schema.sql")},
+			cnf:  mycnf.MyCnf{},
+			want: &config{
+				User:        "shogo",
+				Port:        3306,
+				Schema:      []byte{},
+				Mode:        ExecModeDeploy,
+				RefuseDrift: true,
+			},
+		},
+		{
+			name: "JSON output",
+			args: []string{"schemalex-deploy", "-user", "shogo", "-output", "json", "-dry-run", filepath.Join("testdata", "schema.sql")},
+			cnf:  mycnf.MyCnf{},
+			want: &config{
+				User:   "shogo",
+				Port:   3306,
+				Schema: []byte{},
+				Mode:   ExecModeDeploy,
+				DryRun: true,
+				Output: OutputJSON,
+			},
+		},
+		{
+			name: "diff",
+			args: []string{"schemalex-deploy", "diff", "-user", "shogo", "-output", "json", filepath.Join("testdata", "schema.sql"), filepath.Join("testdata", "schema.sql")},
+			cnf:  mycnf.MyCnf{},
+			want: &config{
+				User:      "shogo",
+				Port:      3306,
+				Schema:    []byte{},
+				OldSchema: []byte{},
+				Mode:      ExecModeDiff,
+				Output:    OutputJSON,
+			},
+		},
+		{
+			name: "ignore rules",
+			args: []string{"schemalex-deploy", "-user", "shogo", "-ignore-tables", "_*_gho, _*_del", "-ignore-columns", "hoge.tmp", "-ignore-table-options", "AUTO_INCREMENT", filepath.Join("testdata", "schema.sql")},
+			cnf:  mycnf.MyCnf{},
+			want: &config{
+				User:               "shogo",
+				Port:               3306,
+				Schema:             []byte{},
+				Mode:               ExecModeDeploy,
+				IgnoreTables:       []string{"_*_gho", "_*_del"},
+				IgnoreColumns:      []string{"hoge.tmp"},
+				IgnoreTableOptions: []string{"AUTO_INCREMENT"},
+			},
+		},
+		{
+			name: "The ignore rules specified in the argument takes precedence",
+			args: []string{"schemalex-deploy", "-user", "shogo", "-ignore-tables", "/^tmp_/", filepath.Join("testdata", "schema.sql")},
+			cnf: mycnf.MyCnf{
+				"schemalex-deploy": map[string]string{
+					"ignore-tables":  "_*_gho",
+					"ignore-columns": "created_at,updated_at",
+				},
+			},
+			want: &config{
+				User:          "shogo",
+				Port:          3306,
+				Schema:        []byte{},
+				Mode:          ExecModeDeploy,
+				IgnoreTables:  []string{"/^tmp_/"},
+				IgnoreColumns: []string{"created_at", "updated_at"},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -3,6 +3,7 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -17,7 +18,10 @@ import (
 	"syscall"
 
 	"github.com/go-sql-driver/mysql"
+	"github.com/shogo82148/schemalex-deploy"
 	"github.com/shogo82148/schemalex-deploy/deploy"
+	"github.com/shogo82148/schemalex-deploy/diff"
+	"github.com/shogo82148/schemalex-deploy/model"
 	"golang.org/x/term"
 )
 
@@ -39,6 +43,10 @@ func _main() error {
 		fmt.Println(getVersion())
 		return nil
 	}
+	if cfn.Mode == ExecModeDiff {
+		// diff mode works offline.
+		return runDiff(cfn)
+	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
@@ -74,23 +82,49 @@ func _main() error {
 
 	case ExecModeImport:
 		return runImport(ctx, db, cfn)
+
+	case ExecModeDumpJSON:
+		return runDumpJSON(ctx, db, cfn)
+
+	case ExecModeRollback:
+		return runRollback(ctx, db, cfn)
+
+	case ExecModeCheck:
+		return runCheck(ctx, db, cfn)
 	}
 
 	return nil
 }
 
 func runDeploy(ctx context.Context, db *deploy.DB, cfn *config) error {
+	// validate
+	if err := validateSchema(cfn.Schema); err != nil {
+		return err
+	}
+
 	// plan
-	plan, err := db.Plan(ctx, string(cfn.Schema))
+	plan, err := db.Plan(ctx, string(cfn.Schema), cfn.planOptions()...)
 	if err != nil {
 		return fmt.Errorf("failed to plan: %w", err)
 	}
 
 	// preview
-	if err := plan.Preview(os.Stderr); err != nil {
+	if err := preview(plan, cfn, os.Stderr); err != nil {
 		return fmt.Errorf("failed to preview: %w", err)
 	}
 
+	// refuse the destructive changes that are not allowed
+	if err := plan.Check(cfn.policy()); err != nil {
+		return err
+	}
+
+	// the data that rolling back the plan can't restore
+	if plan.Down != nil {
+		for _, warning := range plan.Down.Warnings {
+			log.Printf("WARNING: %s", warning)
+		}
+	}
+
 	// dry-run mode: skip deployment
 	if cfn.DryRun {
 		return nil
@@ -113,9 +147,133 @@ func runDeploy(ctx context.Context, db *deploy.DB, cfn *config) error {
 	return nil
 }
 
+func runRollback(ctx context.Context, db *deploy.DB, cfn *config) error {
+	// plan
+	plan, err := db.RollbackPlan(ctx, cfn.planOptions()...)
+	if err != nil {
+		return fmt.Errorf("failed to plan: %w", err)
+	}
+
+	// preview
+	if err := preview(plan, cfn, os.Stderr); err != nil {
+		return fmt.Errorf("failed to preview: %w", err)
+	}
+
+	// refuse the destructive changes that are not allowed
+	if err := plan.Check(cfn.policy()); err != nil {
+		return err
+	}
+
+	// dry-run mode: skip rollback
+	if cfn.DryRun {
+		return nil
+	}
+
+	// ask to approve
+	if !cfn.AutoApprove {
+		if result, err := approved(ctx); err != nil {
+			return err
+		} else if !result {
+			return errors.New("the rollback was cancelled")
+		}
+	}
+
+	// rollback
+	if err := db.Rollback(ctx, plan); err != nil {
+		return fmt.Errorf("failed to roll back: %w", err)
+	}
+
+	return nil
+}
+
+// validateSchema reports the problems that MySQL would report on deploying the schema.
+func validateSchema(schema []byte) error {
+	p := schemalex.New(schemalex.WithPositions(true))
+	stmts, err := p.Parse(schema)
+	if err != nil {
+		return fmt.Errorf("failed to parse the schema: %w", err)
+	}
+
+	diags := model.Validate(stmts)
+	for _, d := range diags {
+		log.Print(d.String())
+	}
+	if diags.HasErrors() {
+		return errors.New("the schema is invalid")
+	}
+	return nil
+}
+
+func runDiff(cfn *config) error {
+	// validate
+	if err := validateSchema(cfn.Schema); err != nil {
+		return err
+	}
+
+	p := schemalex.New()
+	from, err := p.ParseString(string(cfn.OldSchema))
+	if err != nil {
+		return fmt.Errorf("failed to parse the old schema: %w", err)
+	}
+	to, err := p.ParseString(string(cfn.Schema))
+	if err != nil {
+		return fmt.Errorf("failed to parse the new schema: %w", err)
+	}
+
+	opts := []diff.Option{
+		diff.WithTransaction(false),
+		diff.WithIndent(" ", 2),
+	}
+	if ignore := cfn.ignore(); ignore != nil {
+		opts = append(opts, diff.WithIgnore(ignore))
+	}
+	stmts, err := diff.Diff(from, to, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to plan: %w", err)
+	}
+	plan := &deploy.Plan{
+		From:    string(cfn.OldSchema),
+		To:      string(cfn.Schema),
+		Stmts:   stmts,
+		Changes: diff.Classify(stmts),
+	}
+
+	// the statements are the result of the command, so write them to stdout.
+	return preview(plan, cfn, os.Stdout)
+}
+
+func runCheck(ctx context.Context, db *deploy.DB, cfn *config) error {
+	drift, err := db.Drift(ctx, cfn.planOptions()...)
+	if err != nil {
+		return fmt.Errorf("failed to check drift: %w", err)
+	}
+
+	// preview the statements to migrate the running database to the recorded revision
+	if err := preview(drift, cfn, os.Stderr); err != nil {
+		return fmt.Errorf("failed to preview: %w", err)
+	}
+
+	if len(drift.Stmts) > 0 {
+		return errors.New("the running database differs from the recorded revision")
+	}
+	log.Print("no drift detected")
+	return nil
+}
+
+// preview writes the plan in the output format.
+// The JSON is always written to stdout, and the text is written to w.
+func preview(plan *deploy.Plan, cfn *config, w io.Writer) error {
+	if cfn.Output == OutputJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(plan)
+	}
+	return plan.Preview(w)
+}
+
 func runImport(ctx context.Context, db *deploy.DB, cfn *config) error {
 	// load schema
-	sqlText, err := db.LoadSchema(ctx)
+	sqlText, err := db.LoadSchema(ctx, cfn.planOptions()...)
 	if err != nil {
 		return fmt.Errorf("failed to load schema: %w", err)
 	}
@@ -152,6 +310,30 @@ func runImport(ctx context.Context, db *deploy.DB, cfn *config) error {
 	return nil
 }
 
+func runDumpJSON(ctx context.Context, db *deploy.DB, cfn *config) error {
+	sqlText := string(cfn.Schema)
+	if cfn.Schema == nil {
+		// no schema file is given, load schema from the database
+		var err error
+		sqlText, err = db.LoadSchema(ctx, cfn.planOptions()...)
+		if err != nil {
+			return fmt.Errorf("failed to load schema: %w", err)
+		}
+	}
+
+	stmts, err := schemalex.New().ParseString(sqlText)
+	if err != nil {
+		return fmt.Errorf("failed to parse the schema: %w", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(stmts); err != nil {
+		return fmt.Errorf("failed to encode the schema: %w", err)
+	}
+	return nil
+}
+
 func approved(ctx context.Context) (bool, error) {
 	type result struct {
 		line string
@@ -160,9 +342,10 @@ func approved(ctx context.Context) (bool, error) {
 	if !term.IsTerminal(int(os.Stdin.Fd())) {
 		return false, errors.New("tty is required")
 	}
-	fmt.Println("Do you want to perform these actions?")
-	fmt.Println("Only 'yes' will be accepted to confirm.")
-	fmt.Print("Enter a value: ")
+	// write the prompt to stderr, so that it doesn't break the plan written to stdout by -output json.
+	fmt.Fprintln(os.Stderr, "Do you want to perform these actions?")
+	fmt.Fprintln(os.Stderr, "Only 'yes' will be accepted to confirm.")
+	fmt.Fprint(os.Stderr, "Enter a value: ")
 
 	ch := make(chan result, 1)
 	go func() {
@@ -7,12 +7,14 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/shogo82148/schemalex-deploy"
 	"github.com/shogo82148/schemalex-deploy/diff"
+	"github.com/shogo82148/schemalex-deploy/format"
 )
 
 // DB is the target of deploying a DDL schema.
@@ -36,28 +38,38 @@ func (db *DB) Close() error {
 	return db.db.Close()
 }
 
+// Plan is a series of statements to migrate the schema.
 type Plan struct {
 	From  string
 	To    string
 	Stmts diff.Stmts
+
+	// Changes are the changes made by the statements, classified by their risks.
+	Changes []*diff.Change
+
+	// Warnings are the notes about the statements, which Preview shows as comments.
+	Warnings []string
+
+	// Down is the plan to revert the migration.
+	// Its warnings describe the data that the reverse migration can't restore.
+	Down *Plan
 }
 
 // Plan generates a series statements to migrate from the current one to the new schema.
-func (db *DB) Plan(ctx context.Context, schema string) (*Plan, error) {
+func (db *DB) Plan(ctx context.Context, schema string, options ...PlanOption) (*Plan, error) {
 	latest, err := getLatestVersion(ctx, db.db)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get the latest schema: %w", err)
 	}
 
-	p := schemalex.New()
-	opts := []diff.Option{
-		diff.WithTransaction(false),
-		diff.WithIndent(" ", 2),
+	if err := db.refuseDrift(ctx, options); err != nil {
+		return nil, err
 	}
 
-	current, err := db.LoadSchema(ctx)
-	if err == nil {
-		opts = append(opts, diff.WithCurrentSchema(current))
+	p := schemalex.New()
+	opts, err := db.diffOptions(ctx, options)
+	if err != nil {
+		return nil, err
 	}
 
 	stmts1, err := p.ParseString(latest.SQLText)
@@ -75,15 +87,54 @@ func (db *DB) Plan(ctx context.Context, schema string) (*Plan, error) {
 		return nil, fmt.Errorf("failed to plan: %w", err)
 	}
 
+	// plan the rollback with the same options as RollbackPlan does, such as the ignore rules.
+	// the current schema is the one before deploying, so it doesn't tell the names of the indexes to revert.
+	downOpts := append(slices.Clone(opts), diff.WithCurrentSchema(""))
+	down, warnings, err := planDown(stmts1, stmts2, downOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan the rollback: %w", err)
+	}
+
 	return &Plan{
-		From:  latest.SQLText,
-		To:    schema,
-		Stmts: stmts,
+		From:    latest.SQLText,
+		To:      schema,
+		Stmts:   stmts,
+		Changes: diff.Classify(stmts),
+		Down: &Plan{
+			From:     schema,
+			To:       latest.SQLText,
+			Stmts:    down,
+			Changes:  diff.Classify(down),
+			Warnings: warnings,
+		},
 	}, nil
 }
 
+// Preview writes the statements of the plan to w.
+// The risky changes and the changes of the column types are written as comments before the statements.
 func (plan *Plan) Preview(w io.Writer) error {
+	for _, warning := range plan.Warnings {
+		_, err := fmt.Fprintf(w, "-- WARNING: %s\n", warning)
+		if err != nil {
+			return err
+		}
+	}
 	for _, stmt := range plan.Stmts {
+		for _, c := range plan.Changes {
+			if c.Stmt != stmt {
+				continue
+			}
+			comment := c.String()
+			if change, ok := c.Clause.(*diff.ChangeColumn); ok {
+				// show how the values survive the new type, even if the change is safe.
+				comment += fmt.Sprintf(" (%s: %s)", change.To.Name.Quoted(), c.DataLoss)
+			} else if c.Risk == diff.RiskSafe {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "-- %s\n", comment); err != nil {
+				return err
+			}
+		}
 		_, err := fmt.Fprintf(w, "%s;\n", stmt.String())
 		if err != nil {
 			return err
@@ -110,6 +161,173 @@ func (db *DB) Deploy(ctx context.Context, plan *Plan) error {
 		return errors.New("detected unexpected change")
 	}
 
+	err = migrate(ctx, tx, plan, func() error {
+		log.Printf("updating the schema information")
+		rev := &schemalexRevision{
+			SQLText:    plan.To,
+			UpgradedAt: time.Now(),
+		}
+		if plan.Down != nil {
+			var buf strings.Builder
+			if err := plan.Down.Preview(&buf); err != nil {
+				return err
+			}
+			rev.DownSQLText = buf.String()
+		}
+		return updateLatestVersion(ctx, tx, rev)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	log.Printf("done")
+	return nil
+}
+
+// RollbackPlan generates a series statements to revert the latest revision to the previous one.
+func (db *DB) RollbackPlan(ctx context.Context, options ...PlanOption) (*Plan, error) {
+	tx, err := db.db.BeginTx(ctx, &sql.TxOptions{
+		ReadOnly: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Commit()
+
+	latest, err := getLatestVersionTx(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the latest version: %w", err)
+	}
+	if latest.ID == 0 {
+		return nil, errors.New("no revision to roll back")
+	}
+	deployed, err := getDeployedVersionsTx(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the deployed versions: %w", err)
+	}
+	if len(deployed) < 2 {
+		return nil, errors.New("no previous revision to roll back to")
+	}
+	previous, err := getVersionTx(ctx, tx, deployed[len(deployed)-2])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the previous version: %w", err)
+	}
+
+	if err := db.refuseDrift(ctx, options); err != nil {
+		return nil, err
+	}
+
+	p := schemalex.New()
+	opts, err := db.diffOptions(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	stmts1, err := p.ParseString(previous.SQLText)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the previous schema: %w", err)
+	}
+
+	stmts2, err := p.ParseString(latest.SQLText)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the latest schema: %w", err)
+	}
+
+	stmts, warnings, err := planDown(stmts1, stmts2, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan the rollback: %w", err)
+	}
+
+	return &Plan{
+		From:     latest.SQLText,
+		To:       previous.SQLText,
+		Stmts:    stmts,
+		Changes:  diff.Classify(stmts),
+		Warnings: warnings,
+	}, nil
+}
+
+// Rollback reverts the latest revision according to the plan.
+// The history of the schema information is kept, and the rollback is recorded as a new revision
+// that has the schema of the previous revision.
+func (db *DB) Rollback(ctx context.Context, plan *Plan) error {
+	log.Printf("starting to roll back")
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	latest, err := getLatestVersionTx(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("failed to get the latest version: %w", err)
+	}
+	if latest.ID == 0 || latest.SQLText != plan.From {
+		return errors.New("detected unexpected change")
+	}
+	deployed, err := getDeployedVersionsTx(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("failed to get the deployed versions: %w", err)
+	}
+	if len(deployed) < 2 {
+		return errors.New("no previous revision to roll back to")
+	}
+
+	err = migrate(ctx, tx, plan, func() error {
+		log.Printf("updating the schema information")
+		return updateLatestVersion(ctx, tx, &schemalexRevision{
+			SQLText:    plan.To,
+			UpgradedAt: time.Now(),
+			RevertedID: deployed[len(deployed)-1],
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	log.Printf("done")
+	return nil
+}
+
+// diffOptions returns the options of diff.Diff to plan the migration on the database.
+func (db *DB) diffOptions(ctx context.Context, options []PlanOption) ([]diff.Option, error) {
+	var planOpts planOptions
+	for _, opt := range options {
+		opt.apply(&planOpts)
+	}
+
+	opts := []diff.Option{
+		diff.WithTransaction(false),
+		diff.WithIndent(" ", 2),
+	}
+
+	current, err := db.LoadSchema(ctx, options...)
+	if err == nil {
+		opts = append(opts, diff.WithCurrentSchema(current))
+	}
+	if planOpts.ignore != nil {
+		opts = append(opts, diff.WithIgnore(planOpts.ignore))
+	}
+
+	if planOpts.onlineDDL {
+		var version string
+		if err := db.db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to get the server version: %w", err)
+		}
+		opts = append(opts, diff.WithOnlineDDL(version))
+	}
+	return opts, nil
+}
+
+// migrate executes the statements of the plan, and then calls update to update the schema information.
+func migrate(ctx context.Context, tx *sql.Tx, plan *Plan, update func() error) error {
 	// disable foreign key checks during the migration.
 	if _, err := tx.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 0"); err != nil {
 		return fmt.Errorf("failed to disable foreign key checks: %w", err)
@@ -124,12 +342,7 @@ func (db *DB) Deploy(ctx context.Context, plan *Plan) error {
 			return fmt.Errorf("failed to execute %q: %w", stmt.String(), err)
 		}
 	}
-	log.Printf("updating the schema information")
-	err = updateLatestVersion(ctx, tx, &schemalexRevision{
-		SQLText:    plan.To,
-		UpgradedAt: time.Now(),
-	})
-	if err != nil {
+	if err := update(); err != nil {
 		return fmt.Errorf("failed to update the schema information: %w", err)
 	}
 
@@ -137,15 +350,21 @@ func (db *DB) Deploy(ctx context.Context, plan *Plan) error {
 	if _, err := tx.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 1"); err != nil {
 		return fmt.Errorf("failed to disable foreign key checks: %w", err)
 	}
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit: %w", err)
-	}
-	log.Printf("done")
 	return nil
 }
 
 // LoadSchema loads existing table schemas from running database.
-func (db *DB) LoadSchema(ctx context.Context) (string, error) {
+// The schemalex_revision table, which schemalex-deploy manages, is not exported.
+func (db *DB) LoadSchema(ctx context.Context, options ...PlanOption) (string, error) {
+	var planOpts planOptions
+	for _, opt := range options {
+		opt.apply(&planOpts)
+	}
+	ignore := planOpts.ignore
+	if ignore == nil {
+		ignore = &diff.Ignore{}
+	}
+
 	tx, err := db.db.BeginTx(ctx, &sql.TxOptions{
 		ReadOnly: true,
 	})
@@ -154,11 +373,25 @@ func (db *DB) LoadSchema(ctx context.Context) (string, error) {
 	}
 	defer tx.Commit()
 
-	tables, err := showTables(ctx, tx)
+	allTables, err := showTables(ctx, tx)
 	if err != nil {
 		return "", err
 	}
 
+	var tables []string
+	for _, tbl := range allTables {
+		if tbl == revisionTable {
+			continue
+		}
+		ignored, err := ignore.IgnoresTable(tbl)
+		if err != nil {
+			return "", err
+		}
+		if !ignored {
+			tables = append(tables, tbl)
+		}
+	}
+
 	if len(tables) == 0 {
 		return "", nil
 	}
@@ -180,6 +413,11 @@ func (db *DB) LoadSchema(ctx context.Context) (string, error) {
 			return "", fmt.Errorf("failed to get create table %q: %w", tbl, err)
 		}
 
+		sqlText, err = filterTable(sqlText, ignore)
+		if err != nil {
+			return "", fmt.Errorf("failed to filter table %q: %w", tbl, err)
+		}
+
 		if !strings.HasSuffix(sqlText, ";") {
 			sqlText = sqlText + ";"
 		}
@@ -195,6 +433,30 @@ func (db *DB) LoadSchema(ctx context.Context) (string, error) {
 	return strings.Join(statements, "\n"), nil
 }
 
+// filterTable removes the ignored columns and table options from the CREATE TABLE statement.
+// It returns the statement as is if there is nothing to ignore.
+func filterTable(sqlText string, ignore *diff.Ignore) (string, error) {
+	if len(ignore.Columns) == 0 && len(ignore.TableOptions) == 0 {
+		return sqlText, nil
+	}
+	stmts, err := schemalex.New().ParseString(sqlText)
+	if err != nil {
+		return "", err
+	}
+	filtered, err := ignore.Filter(stmts)
+	if err != nil {
+		return "", err
+	}
+	if len(filtered) != 1 || filtered[0] == stmts[0] {
+		return sqlText, nil
+	}
+	var buf strings.Builder
+	if err := format.SQL(&buf, filtered[0], format.WithIndent("  ", 1)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
 // Import imports and updates the schemalex revision using sqlText.
 func (db *DB) Import(ctx context.Context, sqlText string) error {
 	log.Printf("starting to import")
@@ -221,10 +483,23 @@ func (db *DB) Import(ctx context.Context, sqlText string) error {
 	return nil
 }
 
+// revisionTable is the name of the table that stores the schema information.
+const revisionTable = "schemalex_revision"
+
 type schemalexRevision struct {
 	ID         uint64
 	SQLText    string
 	UpgradedAt time.Time
+
+	// DownSQLText is the statements to revert the revision, for reference.
+	// It is informational only. RollbackPlan plans the rollback from the schemas again,
+	// because the statements depend on the database at the time of the deployment,
+	// e.g. the names of the indexes and the ignore rules.
+	DownSQLText string
+
+	// RevertedID is the ID of the revision that the rollback reverted,
+	// or zero if the revision is deployed normally.
+	RevertedID uint64
 }
 
 // get the latest version of schema out of a transaction.
@@ -269,11 +544,78 @@ func getLatestVersionTx(ctx context.Context, tx *sql.Tx) (*schemalexRevision, er
 	return &rev, nil
 }
 
+// get the version of schema by its ID in a transaction.
+func getVersionTx(ctx context.Context, tx *sql.Tx, id uint64) (*schemalexRevision, error) {
+	var rev schemalexRevision
+	row := tx.QueryRowContext(ctx, "SELECT `id`, `sql_text`, `upgraded_at` FROM `schemalex_revision` WHERE `id` = ?", id)
+	if err := row.Scan(&rev.ID, &rev.SQLText, &rev.UpgradedAt); err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// get the IDs of the revisions that are deployed and not reverted, in the order of the deployment.
+// The last one is the revision that the database has now.
+func getDeployedVersionsTx(ctx context.Context, tx *sql.Tx) ([]uint64, error) {
+	// the table created by older versions doesn't have `reverted_id`.
+	query := "SELECT `id`, `reverted_id` FROM `schemalex_revision` ORDER BY `id`"
+	ok, err := hasRevisionColumnTx(ctx, tx, "reverted_id")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		query = "SELECT `id`, NULL FROM `schemalex_revision` ORDER BY `id`"
+	}
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deployed []uint64
+	for rows.Next() {
+		var id uint64
+		var revertedID sql.NullInt64
+		if err := rows.Scan(&id, &revertedID); err != nil {
+			return nil, err
+		}
+		if revertedID.Valid {
+			// the rollback reverts the last deployed revision.
+			if len(deployed) > 0 {
+				deployed = deployed[:len(deployed)-1]
+			}
+			continue
+		}
+		deployed = append(deployed, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return deployed, nil
+}
+
+// hasRevisionColumnTx reports whether the schemalex_revision table has the column.
+func hasRevisionColumnTx(ctx context.Context, tx *sql.Tx, column string) (bool, error) {
+	var name string
+	err := tx.QueryRowContext(ctx, "SELECT `COLUMN_NAME` FROM `information_schema`.`COLUMNS` "+
+		"WHERE `TABLE_SCHEMA` = DATABASE() AND `TABLE_NAME` = 'schemalex_revision' AND `COLUMN_NAME` = ?", column).Scan(&name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // update the schema information.
 func updateLatestVersion(ctx context.Context, tx *sql.Tx, rev *schemalexRevision) error {
 	createTable := "CREATE TABLE IF NOT EXISTS `schemalex_revision` ( " +
 		"`id` BIGINT unsigned NOT NULL AUTO_INCREMENT, " +
 		"`sql_text` LONGTEXT NOT NULL, " +
+		"`down_sql_text` LONGTEXT NULL, " +
+		"`reverted_id` BIGINT unsigned NULL, " +
 		"`upgraded_at` DATETIME(6) NOT NULL, " +
 		"PRIMARY KEY (`id`) " +
 		") ENGINE=InnoDB DEFAULT CHARACTER SET utf8mb4"
@@ -281,8 +623,38 @@ func updateLatestVersion(ctx context.Context, tx *sql.Tx, rev *schemalexRevision
 		return err
 	}
 
-	query := "INSERT INTO `schemalex_revision` (`sql_text`, `upgraded_at`) VALUES (?, ?)"
-	if _, err := tx.ExecContext(ctx, query, rev.SQLText, rev.UpgradedAt); err != nil {
+	// the table created by older versions doesn't have `down_sql_text` and `reverted_id`.
+	columns := []struct {
+		name       string
+		definition string
+	}{
+		{"down_sql_text", "LONGTEXT NULL AFTER `sql_text`"},
+		{"reverted_id", "BIGINT unsigned NULL AFTER `down_sql_text`"},
+	}
+	for _, col := range columns {
+		ok, err := hasRevisionColumnTx(ctx, tx, col.name)
+		if err != nil {
+			return err
+		}
+		if ok {
+			continue
+		}
+		alterTable := "ALTER TABLE `schemalex_revision` ADD COLUMN `" + col.name + "` " + col.definition
+		if _, err := tx.ExecContext(ctx, alterTable); err != nil {
+			return err
+		}
+	}
+
+	var downSQLText sql.NullString
+	if rev.DownSQLText != "" {
+		downSQLText = sql.NullString{String: rev.DownSQLText, Valid: true}
+	}
+	var revertedID sql.NullInt64
+	if rev.RevertedID != 0 {
+		revertedID = sql.NullInt64{Int64: int64(rev.RevertedID), Valid: true}
+	}
+	query := "INSERT INTO `schemalex_revision` (`sql_text`, `down_sql_text`, `reverted_id`, `upgraded_at`) VALUES (?, ?, ?, ?)"
+	if _, err := tx.ExecContext(ctx, query, rev.SQLText, downSQLText, revertedID, rev.UpgradedAt); err != nil {
 		return err
 	}
 	return nil
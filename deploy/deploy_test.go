@@ -3,9 +3,13 @@ package deploy
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/schemalex-deploy"
+	"github.com/shogo82148/schemalex-deploy/diff"
 	"github.com/shogo82148/schemalex-deploy/internal/database"
 	"github.com/shogo82148/schemalex-deploy/internal/util"
 )
@@ -90,6 +94,243 @@ func TestDeploy(t *testing.T) {
 			t.Errorf("schema mismatch (-want,+got):\n%s", diff)
 		}
 	})
+
+	t.Run("rollback", func(t *testing.T) {
+		plan, err := db.RollbackPlan(ctx)
+		if err != nil {
+			t.Fatalf("failed to plan: %v", err)
+		}
+		if len(plan.Warnings) != 0 {
+			t.Errorf("want no warning, but got %v", plan.Warnings)
+		}
+
+		if err := db.Rollback(ctx, plan); err != nil {
+			t.Fatalf("failed to roll back: %v", err)
+		}
+
+		hoge, err := showColumns(ctx, db.db, "hoge")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hoge) != 1 {
+			t.Errorf("want `hoge` has one column, but %d columns", len(hoge))
+		}
+
+		latest, err := getLatestVersion(ctx, db.db)
+		if err != nil {
+			t.Fatalf("failed to get the latest version: %v", err)
+		}
+		if diff := cmp.Diff(plan.To, latest.SQLText); diff != "" {
+			t.Errorf("schema mismatch (-want,+got):\n%s", diff)
+		}
+
+		// the history is kept, but the reverted revision can't be rolled back to.
+		if _, err := db.RollbackPlan(ctx); err == nil {
+			t.Error("want error for rolling back the first revision, got nil")
+		}
+	})
+
+	t.Run("drift", func(t *testing.T) {
+		drift, err := db.Drift(ctx)
+		if err != nil {
+			t.Fatalf("failed to check drift: %v", err)
+		}
+		if len(drift.Stmts) != 0 {
+			t.Errorf("want no drift, but got %v", drift.Stmts)
+		}
+
+		// someone changes the schema manually.
+		if _, err := db.db.ExecContext(ctx, "ALTER TABLE `hoge` ADD COLUMN `manual` INTEGER"); err != nil {
+			t.Fatal(err)
+		}
+
+		drift, err = db.Drift(ctx)
+		if err != nil {
+			t.Fatalf("failed to check drift: %v", err)
+		}
+		var got []string
+		for _, stmt := range drift.Stmts {
+			got = append(got, stmt.String())
+		}
+		if diff := cmp.Diff([]string{"ALTER TABLE `hoge` DROP COLUMN `manual`"}, got); diff != "" {
+			t.Errorf("drift mismatch (-want,+got):\n%s", diff)
+		}
+
+		_, err = db.Plan(ctx, drift.To, WithDriftCheck(true))
+		var derr *DriftError
+		if !errors.As(err, &derr) {
+			t.Errorf("want *DriftError, got %v", err)
+		}
+	})
+}
+
+func TestPlanDown(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		stmts    []string
+		warnings []string
+	}{
+		{
+			name:  "drop the created table",
+			from:  "",
+			to:    "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) );",
+			stmts: []string{"DROP TABLE `hoge`"},
+		},
+		{
+			name: "recreate the dropped column",
+			from: "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `c` INTEGER NOT NULL, PRIMARY KEY (`id`) );",
+			to:   "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) );",
+			stmts: []string{
+				"ALTER TABLE `hoge` ADD COLUMN `c` INT (11) NOT NULL AFTER `id`",
+			},
+			warnings: []string{
+				"column `hoge`.`c` is dropped; rolling back recreates it, but can't restore its values",
+			},
+		},
+		{
+			name: "restore the column type",
+			from: "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `c` VARCHAR (20) NOT NULL, PRIMARY KEY (`id`) );",
+			to:   "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `c` VARCHAR (10) NOT NULL, PRIMARY KEY (`id`) );",
+			stmts: []string{
+				"ALTER TABLE `hoge` CHANGE COLUMN `c` `c` VARCHAR (20) NOT NULL",
+			},
+			warnings: []string{
+				"the type of column `hoge`.`c` is changed; rolling back may not restore its values",
+			},
+		},
+		{
+			name: "rename back the renamed table and column",
+			from: "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) );",
+			to: "-- schemalex:renamed-from hoge\n" +
+				"CREATE TABLE `fuga` ( `fuga_id` INTEGER NOT NULL /* schemalex:renamed-from id */, PRIMARY KEY (`fuga_id`) );",
+			stmts: []string{
+				"RENAME TABLE `fuga` TO `hoge`",
+				"ALTER TABLE `hoge` RENAME COLUMN `fuga_id` TO `id`",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := schemalex.New()
+			from, err := p.ParseString(tt.from)
+			if err != nil {
+				t.Fatal(err)
+			}
+			to, err := p.ParseString(tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stmts, warnings, err := planDown(from, to, diff.WithTransaction(false))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, stmt := range stmts {
+				got = append(got, stmt.String())
+			}
+			if diff := cmp.Diff(tt.stmts, got); diff != "" {
+				t.Errorf("statements mismatch (-want,+got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.warnings, warnings); diff != "" {
+				t.Errorf("warnings mismatch (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPlanPreview(t *testing.T) {
+	p := schemalex.New()
+	from, err := p.ParseString("CREATE TABLE `hoge` ( `id` BIGINT NOT NULL, `name` VARCHAR (20) NOT NULL );")
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := p.ParseString("CREATE TABLE `hoge` ( `id` BIGINT NOT NULL, `name` VARCHAR (10) NOT NULL );")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmts, err := diff.Diff(from, to, diff.WithTransaction(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := &Plan{
+		Stmts:   stmts,
+		Changes: diff.Classify(stmts),
+	}
+
+	var buf strings.Builder
+	if err := plan.Preview(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "-- destructive: narrows the type of column `name`, may truncate data (`name`: narrowing)\n" +
+		"ALTER TABLE `hoge` CHANGE COLUMN `name` `name` VARCHAR (10) NOT NULL;\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("preview mismatch (-want,+got):\n%s", diff)
+	}
+	if got := plan.Changes[0].DataLoss; got != diff.DataNarrowing {
+		t.Errorf("want %s, got %s", diff.DataNarrowing, got)
+	}
+}
+
+func TestPlanPreview_Widening(t *testing.T) {
+	p := schemalex.New()
+	from, err := p.ParseString("CREATE TABLE `hoge` ( `id` INT NOT NULL );")
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := p.ParseString("CREATE TABLE `hoge` ( `id` BIGINT NOT NULL );")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmts, err := diff.Diff(from, to, diff.WithTransaction(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := &Plan{
+		Stmts:   stmts,
+		Changes: diff.Classify(stmts),
+	}
+
+	var buf strings.Builder
+	if err := plan.Preview(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "-- blocking: rebuilds the table to change column `id` (`id`: widening)\n" +
+		"ALTER TABLE `hoge` CHANGE COLUMN `id` `id` BIGINT (20) NOT NULL;\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("preview mismatch (-want,+got):\n%s", diff)
+	}
+}
+
+func TestFilterTable(t *testing.T) {
+	const sqlText = "CREATE TABLE `hoge` (\n" +
+		"  `id` int NOT NULL,\n" +
+		"  `tmp` int NOT NULL,\n" +
+		"  PRIMARY KEY (`id`)\n" +
+		") ENGINE=InnoDB AUTO_INCREMENT=10"
+
+	got, err := filterTable(sqlText, &diff.Ignore{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != sqlText {
+		t.Errorf("want the statement as is, got %q", got)
+	}
+
+	got, err = filterTable(sqlText, &diff.Ignore{Columns: []string{"tmp"}, TableOptions: []string{"AUTO_INCREMENT"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "CREATE TABLE `hoge` (\n" +
+		"  `id` INT (11) NOT NULL,\n" +
+		"  PRIMARY KEY (`id`)\n" +
+		") ENGINE = InnoDB"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected statement (-want,+got):\n%s", diff)
+	}
 }
 
 type column struct {
@@ -123,6 +364,44 @@ func showColumns(ctx context.Context, db *sql.DB, table string) ([]*column, erro
 	return columns, nil
 }
 
+func TestComparePlan_ForeignKeyIndex(t *testing.T) {
+	// the output of SHOW CREATE TABLE, after deploying the recorded schema.
+	// MySQL names the index for the anonymous foreign key after its column.
+	const live = "CREATE TABLE `p` (\n" +
+		"  `id` int NOT NULL,\n" +
+		"  PRIMARY KEY (`id`)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;\n" +
+		"CREATE TABLE `t` (\n" +
+		"  `id` int NOT NULL,\n" +
+		"  `pid` int NOT NULL,\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  KEY `pid` (`pid`),\n" +
+		"  CONSTRAINT `t_ibfk_1` FOREIGN KEY (`pid`) REFERENCES `p` (`id`)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci"
+	const recorded = "CREATE TABLE p (\n" +
+		"  id INT NOT NULL,\n" +
+		"  PRIMARY KEY (id)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;\n" +
+		"CREATE TABLE t (\n" +
+		"  id INT NOT NULL,\n" +
+		"  pid INT NOT NULL,\n" +
+		"  PRIMARY KEY (id),\n" +
+		"  FOREIGN KEY (pid) REFERENCES p (id)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;\n"
+
+	plan, err := comparePlan(live, recorded, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Stmts) > 0 {
+		var buf strings.Builder
+		if err := plan.Preview(&buf); err != nil {
+			t.Fatal(err)
+		}
+		t.Errorf("want no drift, got:\n%s", buf.String())
+	}
+}
+
 func TestImport(t *testing.T) {
 	database.SkipIfNoTestDatabase(t)
 
@@ -0,0 +1,103 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/shogo82148/schemalex-deploy"
+	"github.com/shogo82148/schemalex-deploy/diff"
+)
+
+// DriftError is returned if the live schema differs from the recorded revision,
+// e.g. someone has run DDL statements manually.
+type DriftError struct {
+	// Plan is the plan to migrate the live schema to the recorded revision.
+	Plan *Plan
+}
+
+func (e *DriftError) Error() string {
+	var buf strings.Builder
+	buf.WriteString("the live schema differs from the recorded revision:")
+	for _, stmt := range e.Plan.Stmts {
+		buf.WriteString("\n  ")
+		buf.WriteString(stmt.String())
+	}
+	return buf.String()
+}
+
+// Drift compares the live schema with the latest recorded revision.
+// It returns the plan to migrate the live schema to the recorded revision,
+// which has no statements if there is no drift.
+// Plan.Check is not applicable to it, because the plan is just a report.
+func (db *DB) Drift(ctx context.Context, options ...PlanOption) (*Plan, error) {
+	var planOpts planOptions
+	for _, opt := range options {
+		opt.apply(&planOpts)
+	}
+
+	latest, err := getLatestVersion(ctx, db.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the latest schema: %w", err)
+	}
+	live, err := db.LoadSchema(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the live schema: %w", err)
+	}
+
+	return comparePlan(live, latest.SQLText, planOpts.ignore)
+}
+
+// comparePlan returns the plan to migrate the live schema to the recorded one.
+// The live schema is the output of SHOW CREATE TABLE, so the indexes that MySQL creates
+// for the foreign keys are normalized by diff.Diff.
+func comparePlan(live, recorded string, ignore *diff.Ignore) (*Plan, error) {
+	p := schemalex.New()
+	stmts1, err := p.ParseString(live)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the live schema: %w", err)
+	}
+	stmts2, err := p.ParseString(recorded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the latest schema: %w", err)
+	}
+
+	opts := []diff.Option{
+		diff.WithTransaction(false),
+		diff.WithIndent(" ", 2),
+	}
+	if ignore != nil {
+		opts = append(opts, diff.WithIgnore(ignore))
+	}
+	stmts, err := diff.Diff(stmts1, stmts2, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare the schemas: %w", err)
+	}
+
+	return &Plan{
+		From:    live,
+		To:      recorded,
+		Stmts:   stmts,
+		Changes: diff.Classify(stmts),
+	}, nil
+}
+
+// refuseDrift returns a *DriftError if the option to refuse the drift is enabled and the drift exists.
+func (db *DB) refuseDrift(ctx context.Context, options []PlanOption) error {
+	var planOpts planOptions
+	for _, opt := range options {
+		opt.apply(&planOpts)
+	}
+	if !planOpts.refuseDrift {
+		return nil
+	}
+
+	drift, err := db.Drift(ctx, options...)
+	if err != nil {
+		return err
+	}
+	if len(drift.Stmts) > 0 {
+		return &DriftError{Plan: drift}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package deploy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/shogo82148/schemalex-deploy/diff"
+)
+
+type planJSON struct {
+	// From and To are the hashes of the schemas.
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	Statements []*statementJSON `json:"statements"`
+	Warnings   []string         `json:"warnings,omitempty"`
+	Down       *Plan            `json:"down,omitempty"`
+}
+
+type statementJSON struct {
+	SQL     string        `json:"sql"`
+	Table   string        `json:"table,omitempty"`
+	Kind    string        `json:"kind"`
+	Risk    string        `json:"risk"`
+	Changes []*changeJSON `json:"changes,omitempty"`
+}
+
+type changeJSON struct {
+	Clause   string `json:"clause,omitempty"`
+	Kind     string `json:"kind"`
+	Risk     string `json:"risk"`
+	Reason   string `json:"reason,omitempty"`
+	DataLoss string `json:"data_loss,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+// The schemas are represented by the SHA-256 hashes of them, and
+// each statement has the affected table, the kind of the operation and its risk.
+func (plan *Plan) MarshalJSON() ([]byte, error) {
+	changes := plan.Changes
+	if changes == nil {
+		changes = diff.Classify(plan.Stmts)
+	}
+
+	v := &planJSON{
+		From:       revisionHash(plan.From),
+		To:         revisionHash(plan.To),
+		Statements: make([]*statementJSON, 0, len(plan.Stmts)),
+		Warnings:   plan.Warnings,
+		Down:       plan.Down,
+	}
+	for _, stmt := range plan.Stmts {
+		s := &statementJSON{
+			SQL:  stmt.String(),
+			Kind: stmtKind(stmt),
+		}
+		var stmtChanges []*diff.Change
+		for _, c := range changes {
+			if c.Stmt != stmt {
+				continue
+			}
+			stmtChanges = append(stmtChanges, c)
+			s.Table = string(c.Table)
+			if c.Clause == nil {
+				continue
+			}
+			change := &changeJSON{
+				Clause: c.Clause.String(),
+				Kind:   clauseKind(c.Clause),
+				Risk:   c.Risk.String(),
+				Reason: c.Reason,
+			}
+			if _, ok := c.Clause.(*diff.ChangeColumn); ok || c.DataLoss != diff.DataLossless {
+				change.DataLoss = c.DataLoss.String()
+			}
+			s.Changes = append(s.Changes, change)
+		}
+		s.Risk = diff.MaxRisk(stmtChanges).String()
+		v.Statements = append(v.Statements, s)
+	}
+	return json.Marshal(v)
+}
+
+// revisionHash returns the hash of the schema, or an empty string if there is no schema.
+func revisionHash(sqlText string) string {
+	if sqlText == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(sqlText))
+	return hex.EncodeToString(sum[:])
+}
+
+// stmtKind returns the kind of the statement.
+func stmtKind(stmt diff.Stmt) string {
+	switch stmt.(type) {
+	case *diff.CreateTable:
+		return "create_table"
+	case *diff.DropTable:
+		return "drop_table"
+	case *diff.RenameTable:
+		return "rename_table"
+	case *diff.AlterTable:
+		return "alter_table"
+	}
+	return "raw"
+}
+
+// clauseKind returns the kind of the clause of ALTER TABLE.
+func clauseKind(clause diff.AlterClause) string {
+	switch clause.(type) {
+	case *diff.AddColumn:
+		return "add_column"
+	case *diff.DropColumn:
+		return "drop_column"
+	case *diff.ChangeColumn:
+		return "change_column"
+	case *diff.RenameColumn:
+		return "rename_column"
+	case *diff.AddIndex:
+		return "add_index"
+	case *diff.DropIndex:
+		return "drop_index"
+	case *diff.DropForeignKey:
+		return "drop_foreign_key"
+	case *diff.RenameIndex:
+		return "rename_index"
+	case *diff.SetTableOption:
+		return "table_option"
+	case *diff.ConvertCharset:
+		return "convert_charset"
+	}
+	return "unknown"
+}
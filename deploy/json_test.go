@@ -0,0 +1,86 @@
+package deploy
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/schemalex-deploy"
+	"github.com/shogo82148/schemalex-deploy/diff"
+)
+
+func TestPlanMarshalJSON(t *testing.T) {
+	const from = "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) NOT NULL, `tmp` INTEGER NOT NULL );"
+	const to = "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `name` VARCHAR (10) NOT NULL );\n" +
+		"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );"
+
+	p := schemalex.New()
+	stmts1, err := p.ParseString(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmts2, err := p.ParseString(to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmts, err := diff.Diff(stmts1, stmts2, diff.WithTransaction(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := &Plan{
+		From:    from,
+		To:      to,
+		Stmts:   stmts,
+		Changes: diff.Classify(stmts),
+	}
+
+	got, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "from": "` + revisionHash(from) + `",
+  "to": "` + revisionHash(to) + `",
+  "statements": [
+    {
+      "sql": "CREATE TABLE ` + "`fuga`" + ` (\n` + "`id`" + ` INT (11) NOT NULL\n)",
+      "table": "fuga",
+      "kind": "create_table",
+      "risk": "safe"
+    },
+    {
+      "sql": "ALTER TABLE ` + "`hoge`" + ` DROP COLUMN ` + "`tmp`" + `, CHANGE COLUMN ` + "`name` `name`" + ` VARCHAR (10) NOT NULL",
+      "table": "hoge",
+      "kind": "alter_table",
+      "risk": "destructive",
+      "changes": [
+        {
+          "clause": "DROP COLUMN ` + "`tmp`" + `",
+          "kind": "drop_column",
+          "risk": "destructive",
+          "reason": "drops column ` + "`tmp`" + `"
+        },
+        {
+          "clause": "CHANGE COLUMN ` + "`name` `name`" + ` VARCHAR (10) NOT NULL",
+          "kind": "change_column",
+          "risk": "destructive",
+          "reason": "narrows the type of column ` + "`name`" + `, may truncate data",
+          "data_loss": "narrowing"
+        }
+      ]
+    }
+  ]
+}`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("unexpected JSON (-want,+got):\n%s", diff)
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(&Plan{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), `{"from":"","to":"","statements":[]}`+"\n"; got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
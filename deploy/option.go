@@ -0,0 +1,54 @@
+package deploy
+
+import "github.com/shogo82148/schemalex-deploy/diff"
+
+type planOptions struct {
+	onlineDDL   bool
+	ignore      *diff.Ignore
+	refuseDrift bool
+}
+
+// PlanOption is an option of DB.Plan, DB.RollbackPlan and DB.LoadSchema.
+type PlanOption interface {
+	apply(opts *planOptions)
+}
+
+type withOnlineDDL bool
+
+func (opt withOnlineDDL) apply(opts *planOptions) {
+	opts.onlineDDL = bool(opt)
+}
+
+// WithOnlineDDL appends the ALGORITHM and LOCK clauses predicted for the server to each ALTER TABLE statement,
+// so that the server fails instead of running the statement in a more expensive way.
+// See diff.WithOnlineDDL for details.
+func WithOnlineDDL(enabled bool) PlanOption {
+	return withOnlineDDL(enabled)
+}
+
+type withIgnore struct {
+	ignore *diff.Ignore
+}
+
+func (opt withIgnore) apply(opts *planOptions) {
+	opts.ignore = opt.ignore
+}
+
+// WithIgnore leaves the tables, the columns and the table options untouched.
+// LoadSchema doesn't export them either.
+// See diff.WithIgnore for details.
+func WithIgnore(ignore *diff.Ignore) PlanOption {
+	return withIgnore{ignore}
+}
+
+type withDriftCheck bool
+
+func (opt withDriftCheck) apply(opts *planOptions) {
+	opts.refuseDrift = bool(opt)
+}
+
+// WithDriftCheck makes DB.Plan and DB.RollbackPlan return a *DriftError
+// if the live schema differs from the latest recorded revision.
+func WithDriftCheck(enabled bool) PlanOption {
+	return withDriftCheck(enabled)
+}
@@ -0,0 +1,62 @@
+package deploy
+
+import (
+	"strings"
+
+	"github.com/shogo82148/schemalex-deploy/diff"
+)
+
+// Policy is the policy to accept the destructive changes.
+type Policy struct {
+	// AllowDestructive allows the destructive changes on all tables.
+	AllowDestructive bool
+
+	// AllowedTables is the list of the tables whose destructive changes are allowed.
+	AllowedTables []string
+}
+
+// allows reports whether the policy allows the change.
+func (p *Policy) allows(c *diff.Change) bool {
+	if c.Risk < diff.RiskDestructive || p.AllowDestructive {
+		return true
+	}
+	for _, table := range p.AllowedTables {
+		if strings.EqualFold(table, string(c.Table)) {
+			return true
+		}
+	}
+	return false
+}
+
+// DestructiveChangeError is returned by Plan.Check
+// if the plan contains the destructive changes that the policy doesn't allow.
+type DestructiveChangeError struct {
+	Changes []*diff.Change
+}
+
+func (e *DestructiveChangeError) Error() string {
+	var buf strings.Builder
+	buf.WriteString("the plan contains destructive changes that are not allowed:")
+	for _, c := range e.Changes {
+		buf.WriteString("\n  ")
+		buf.WriteString(c.Table.Quoted())
+		buf.WriteString(": ")
+		buf.WriteString(c.Reason)
+	}
+	return buf.String()
+}
+
+// Check returns a *DestructiveChangeError if the plan contains the destructive changes
+// that the policy doesn't allow.
+func (plan *Plan) Check(policy *Policy) error {
+	var denied []*diff.Change
+	for _, c := range plan.Changes {
+		if !policy.allows(c) {
+			denied = append(denied, c)
+		}
+	}
+	if len(denied) > 0 {
+		return &DestructiveChangeError{Changes: denied}
+	}
+	return nil
+}
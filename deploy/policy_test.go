@@ -0,0 +1,70 @@
+package deploy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shogo82148/schemalex-deploy"
+	"github.com/shogo82148/schemalex-deploy/diff"
+)
+
+func TestPlanCheck(t *testing.T) {
+	p := schemalex.New()
+	from, err := p.ParseString("CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` INTEGER );\n" +
+		"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );")
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := p.ParseString("CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmts, err := diff.Diff(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := &Plan{
+		Stmts:   stmts,
+		Changes: diff.Classify(stmts),
+	}
+
+	tests := []struct {
+		name   string
+		policy *Policy
+		denied int
+	}{
+		{
+			name:   "default",
+			policy: &Policy{},
+			denied: 2,
+		},
+		{
+			name:   "allow destructive",
+			policy: &Policy{AllowDestructive: true},
+			denied: 0,
+		},
+		{
+			name:   "allow on a table",
+			policy: &Policy{AllowedTables: []string{"HOGE"}},
+			denied: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := plan.Check(tt.policy)
+			if tt.denied == 0 {
+				if err != nil {
+					t.Errorf("want no error, got %v", err)
+				}
+				return
+			}
+			var derr *DestructiveChangeError
+			if !errors.As(err, &derr) {
+				t.Fatalf("want DestructiveChangeError, got %v", err)
+			}
+			if len(derr.Changes) != tt.denied {
+				t.Errorf("want %d denied changes, got %d: %v", tt.denied, len(derr.Changes), err)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package deploy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shogo82148/schemalex-deploy/diff"
+	"github.com/shogo82148/schemalex-deploy/internal/util"
+	"github.com/shogo82148/schemalex-deploy/model"
+)
+
+// planDown generates the statements to revert the migration from the `from` schema to the `to` schema,
+// and the warnings about the data that the statements can't restore.
+func planDown(from, to model.Stmts, opts ...diff.Option) (diff.Stmts, []string, error) {
+	stmts, err := diff.Diff(to, reverseRenames(from, to), opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stmts, irreversibleChanges(from, to), nil
+}
+
+// reverseRenames returns the copy of the `from` schema with the renamed-from annotations
+// that point to the new names in the `to` schema,
+// so that the reverse migration renames the tables and the columns back.
+func reverseRenames(from, to model.Stmts) model.Stmts {
+	ret := make(model.Stmts, 0, len(from))
+	for _, stmt := range from {
+		table, ok := stmt.(*model.Table)
+		if !ok {
+			ret = append(ret, stmt)
+			continue
+		}
+
+		table = table.Clone()
+		table.RenamedFrom = model.MaybeIdent{}
+		for _, col := range table.Columns {
+			col.RenamedFrom = model.MaybeIdent{}
+		}
+
+		newTable, renamed := lookupNewTable(table, from, to)
+		if newTable == nil {
+			ret = append(ret, table)
+			continue
+		}
+		if renamed {
+			table.RenamedFrom = model.MaybeIdent{Ident: newTable.Name, Valid: true}
+		}
+		for _, col := range table.Columns {
+			if newCol, renamed := lookupNewColumn(col, table, newTable); newCol != nil && renamed {
+				col.RenamedFrom = model.MaybeIdent{Ident: newCol.Name, Valid: true}
+			}
+		}
+		ret = append(ret, table)
+	}
+	return ret
+}
+
+// irreversibleChanges returns the warnings about the changes
+// that the reverse migration can't restore the data of.
+func irreversibleChanges(from, to model.Stmts) []string {
+	var warnings []string
+	for _, stmt := range from {
+		table, ok := stmt.(*model.Table)
+		if !ok {
+			continue
+		}
+
+		newTable, _ := lookupNewTable(table, from, to)
+		if newTable == nil {
+			warnings = append(warnings, fmt.Sprintf(
+				"table %s is dropped; rolling back recreates it, but can't restore its rows",
+				util.Backquote(string(table.Name)),
+			))
+			continue
+		}
+
+		for _, col := range table.Columns {
+			name := util.Backquote(string(table.Name)) + "." + util.Backquote(string(col.Name))
+			newCol, _ := lookupNewColumn(col, table, newTable)
+			if newCol == nil {
+				warnings = append(warnings, fmt.Sprintf(
+					"column %s is dropped; rolling back recreates it, but can't restore its values",
+					name,
+				))
+				continue
+			}
+			if !equalColumnType(col, newCol) {
+				warnings = append(warnings, fmt.Sprintf(
+					"the type of column %s is changed; rolling back may not restore its values",
+					name,
+				))
+			}
+		}
+	}
+	return warnings
+}
+
+// lookupNewTable looks for the table in the `to` schema that the table in the `from` schema migrates to.
+// renamed reports whether the table is renamed by the renamed-from annotation.
+func lookupNewTable(table *model.Table, from, to model.Stmts) (newTable *model.Table, renamed bool) {
+	if stmt, ok := to.Lookup(table.ID()); ok {
+		if t, ok := stmt.(*model.Table); ok {
+			return t, false
+		}
+	}
+	for _, stmt := range to {
+		t, ok := stmt.(*model.Table)
+		if !ok || !t.RenamedFrom.Valid {
+			continue
+		}
+		if !strings.EqualFold(string(t.RenamedFrom.Ident), string(table.Name)) {
+			continue
+		}
+		if _, ok := from.Lookup(t.ID()); ok {
+			// the new name is already used; the annotation is outdated.
+			continue
+		}
+		return t, true
+	}
+	return nil, false
+}
+
+// lookupNewColumn looks for the column in newTable that the column in table migrates to.
+// renamed reports whether the column is renamed by the renamed-from annotation.
+func lookupNewColumn(col *model.TableColumn, table, newTable *model.Table) (newCol *model.TableColumn, renamed bool) {
+	if c, ok := newTable.LookupColumn(col.ID()); ok {
+		return c, false
+	}
+	for _, c := range newTable.Columns {
+		if !c.RenamedFrom.Valid || !strings.EqualFold(string(c.RenamedFrom.Ident), string(col.Name)) {
+			continue
+		}
+		if _, ok := table.LookupColumn(c.ID()); ok {
+			// the new name is already used; the annotation is outdated.
+			continue
+		}
+		return c, true
+	}
+	return nil, false
+}
+
+// equalColumnType reports whether the columns store the same set of values.
+func equalColumnType(a, b *model.TableColumn) bool {
+	for _, attr := range a.Compare(b) {
+		switch attr {
+		case model.ColumnAttributeType,
+			model.ColumnAttributeLength,
+			model.ColumnAttributeUnsigned,
+			model.ColumnAttributeCharacterSet,
+			model.ColumnAttributeCollation,
+			model.ColumnAttributeEnumValues,
+			model.ColumnAttributeSetValues:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package diff
+
+// orderAutoIncrementChanges moves the changes of AUTO_INCREMENT around the changes of the keys,
+// because MySQL requires AUTO_INCREMENT columns to be indexed.
+// The columns lose AUTO_INCREMENT before the keys are dropped,
+// and the columns get AUTO_INCREMENT after the new keys are added, e.g.
+//
+//	MODIFY COLUMN `id` INT NOT NULL, DROP PRIMARY KEY, ADD PRIMARY KEY (`a`), MODIFY COLUMN `a` INT NOT NULL AUTO_INCREMENT
+//
+// so that each prefix of the clauses leaves a valid table.
+// The columns that change their positions are not moved, because the positions may depend on the other clauses.
+func (ctx *alterCtx) orderAutoIncrementChanges() error {
+	var dropped, added, others []AlterClause
+	lastIndex := -1
+	for _, clause := range ctx.clauses {
+		if c, ok := clause.(*ChangeColumn); ok && c.Position == nil {
+			switch {
+			case c.From.AutoIncrement && !c.To.AutoIncrement:
+				dropped = append(dropped, c)
+				continue
+			case !c.From.AutoIncrement && c.To.AutoIncrement:
+				added = append(added, c)
+				continue
+			}
+		}
+		if _, ok := clause.(*AddIndex); ok {
+			lastIndex = len(others)
+		}
+		others = append(others, clause)
+	}
+	if len(dropped) == 0 && len(added) == 0 {
+		return nil
+	}
+
+	clauses := make([]AlterClause, 0, len(ctx.clauses))
+	clauses = append(clauses, dropped...)
+	clauses = append(clauses, others[:lastIndex+1]...)
+	clauses = append(clauses, added...)
+	clauses = append(clauses, others[lastIndex+1:]...)
+	ctx.clauses = clauses
+	return nil
+}
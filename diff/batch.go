@@ -0,0 +1,254 @@
+package diff
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/shogo82148/schemalex-deploy/model"
+)
+
+// AlterBatching is the strategy to batch the clauses of ALTER TABLE statements.
+type AlterBatching int
+
+// List of the strategies of batching.
+const (
+	// BatchAll merges all changes of a table into one ALTER TABLE statement.
+	// The table is rebuilt at most once.
+	BatchAll AlterBatching = iota
+
+	// BatchPerClause generates an ALTER TABLE statement for each clause.
+	BatchPerClause
+
+	// BatchByAlgorithm groups the clauses by the algorithm that MySQL runs them with,
+	// so that cheap changes are not merged with the ones that rebuild the table.
+	BatchByAlgorithm
+)
+
+// latestServerVersion is the server version assumed for BatchByAlgorithm without WithOnlineDDL.
+var latestServerVersion = ServerVersion{Major: 8, Minor: 4, Patch: 0}
+
+// alterUnit is a series of the clauses that must be in the same statement.
+type alterUnit struct {
+	clauses   []AlterClause
+	keys      set
+	onlineDDL OnlineDDL
+}
+
+// alterGroup is a series of the units that are in the same statement.
+type alterGroup struct {
+	units     []*alterUnit
+	algorithm Algorithm
+}
+
+// splitAlterTable splits the statement according to the strategy.
+func splitAlterTable(stmt *AlterTable, batching AlterBatching, version ServerVersion) []*AlterTable {
+	if batching == BatchAll || len(stmt.Clauses) <= 1 {
+		return []*AlterTable{stmt}
+	}
+
+	units := alterUnits(stmt, version)
+	var groups []*alterGroup
+	switch batching {
+	case BatchPerClause:
+		for _, u := range units {
+			groups = append(groups, &alterGroup{units: []*alterUnit{u}})
+		}
+	case BatchByAlgorithm:
+		groups = groupByAlgorithm(units)
+	default:
+		return []*AlterTable{stmt}
+	}
+
+	ret := make([]*AlterTable, 0, len(groups))
+	for _, g := range groups {
+		var clauses []AlterClause
+		for _, u := range g.units {
+			clauses = append(clauses, u.clauses...)
+		}
+		ret = append(ret, &AlterTable{
+			Table:     stmt.Table,
+			Clauses:   clauses,
+			OnlineDDL: stmt.OnlineDDL,
+		})
+	}
+	return ret
+}
+
+// alterUnits splits the clauses into units.
+// The changes of the primary key, AUTO_INCREMENT columns and the indexes on them are kept in one unit,
+// because MySQL requires AUTO_INCREMENT columns to be indexed at the end of every statement.
+// The unit is placed at the position of its last clause,
+// so that it follows the clauses that its clauses depend on, e.g. adding the column of the new primary key.
+func alterUnits(stmt *AlterTable, version ServerVersion) []*alterUnit {
+	autoIncrements := autoIncrementColumns(stmt)
+	var units []*alterUnit
+	var keyUnit *alterUnit
+	for _, c := range stmt.Clauses {
+		u := &alterUnit{
+			clauses:   []AlterClause{c},
+			keys:      clauseKeys(c),
+			onlineDDL: (&AlterTable{Table: stmt.Table, Clauses: []AlterClause{c}}).PredictOnlineDDL(version),
+		}
+		if !changesPrimaryKey(c, autoIncrements) {
+			units = append(units, u)
+			continue
+		}
+		if keyUnit == nil {
+			keyUnit = u
+			units = append(units, u)
+			continue
+		}
+		keyUnit.clauses = append(keyUnit.clauses, c)
+		for key := range u.keys {
+			keyUnit.keys.Add(key)
+		}
+		keyUnit.onlineDDL = (&AlterTable{Table: stmt.Table, Clauses: keyUnit.clauses}).PredictOnlineDDL(version)
+		units = slices.DeleteFunc(units, func(v *alterUnit) bool { return v == keyUnit })
+		units = append(units, keyUnit)
+	}
+	return units
+}
+
+// groupByAlgorithm groups the units by their algorithms, keeping the order of the units that depend on each other.
+func groupByAlgorithm(units []*alterUnit) []*alterGroup {
+	var groups []*alterGroup
+	for _, u := range units {
+		// the unit must be placed after the units that touch the same objects.
+		start := 0
+		for i, g := range groups {
+			for _, v := range g.units {
+				if conflicts(u.keys, v.keys) {
+					start = i
+				}
+			}
+		}
+
+		var found *alterGroup
+		for _, g := range groups[start:] {
+			if g.algorithm == u.onlineDDL.Algorithm {
+				found = g
+				break
+			}
+		}
+		if found == nil {
+			found = &alterGroup{algorithm: u.onlineDDL.Algorithm}
+			groups = append(groups, found)
+		}
+		found.units = append(found.units, u)
+	}
+	return groups
+}
+
+// conflictsAll is the key of the clauses that conflict with any other clauses.
+const conflictsAll = "*"
+
+func conflicts(a, b set) bool {
+	if a.Contains(conflictsAll) || b.Contains(conflictsAll) {
+		return true
+	}
+	return a.Intersect(b).Cardinality() > 0
+}
+
+// clauseKeys returns the objects that the clause touches.
+func clauseKeys(clause AlterClause) set {
+	keys := newSet()
+	addColumn := func(col *model.TableColumn) {
+		if col != nil {
+			keys.Add(col.ID())
+		}
+	}
+	addPosition := func(pos *ColumnPosition) {
+		if pos != nil {
+			addColumn(pos.After)
+		}
+	}
+	addIndex := func(idx *model.Index, name model.Ident) {
+		if name != "" {
+			keys.Add("index#" + strings.ToLower(string(name)))
+		}
+		if n := getIndexName(idx); n.Valid {
+			keys.Add("index#" + strings.ToLower(string(n.Ident)))
+		}
+		for _, col := range idx.Columns {
+			keys.Add(model.NewTableColumn(string(col.Name)).ID())
+		}
+	}
+
+	switch c := clause.(type) {
+	case *AddColumn:
+		addColumn(c.Column)
+		addPosition(c.Position)
+	case *DropColumn:
+		addColumn(c.Column)
+	case *ChangeColumn:
+		addColumn(c.From)
+		addColumn(c.To)
+		addPosition(c.Position)
+	case *RenameColumn:
+		addColumn(c.From)
+		addColumn(c.To)
+	case *AddIndex:
+		addIndex(c.Index, "")
+	case *DropIndex:
+		addIndex(c.Index, c.Name)
+	case *DropForeignKey:
+		addIndex(c.Index, c.Name)
+	case *RenameIndex:
+		addIndex(c.From, c.Name)
+		addIndex(c.To, "")
+	default:
+		// e.g. the default character set affects the following column definitions.
+		keys.Add(conflictsAll)
+	}
+	return keys
+}
+
+// autoIncrementColumns returns the IDs of the columns that are AUTO_INCREMENT before or after the statement.
+func autoIncrementColumns(stmt *AlterTable) set {
+	ids := newSet()
+	for _, col := range stmt.Table.Columns {
+		if col.AutoIncrement {
+			ids.Add(col.ID())
+		}
+	}
+	for _, c := range stmt.Clauses {
+		switch c := c.(type) {
+		case *DropColumn:
+			if c.Column.AutoIncrement {
+				ids.Add(c.Column.ID())
+			}
+		case *ChangeColumn:
+			if c.From.AutoIncrement {
+				ids.Add(c.From.ID())
+			}
+		}
+	}
+	return ids
+}
+
+// changesPrimaryKey reports whether the clause changes the primary key, an AUTO_INCREMENT column,
+// or an index on an AUTO_INCREMENT column.
+func changesPrimaryKey(clause AlterClause, autoIncrements set) bool {
+	indexesAutoIncrement := func(idx *model.Index) bool {
+		for _, col := range idx.Columns {
+			if autoIncrements.Contains(model.NewTableColumn(string(col.Name)).ID()) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch c := clause.(type) {
+	case *AddIndex:
+		return c.Index.Kind == model.IndexKindPrimaryKey || indexesAutoIncrement(c.Index)
+	case *DropIndex:
+		return c.Index.Kind == model.IndexKindPrimaryKey || indexesAutoIncrement(c.Index)
+	case *AddColumn:
+		return c.Column.AutoIncrement
+	case *DropColumn:
+		return c.Column.AutoIncrement
+	case *ChangeColumn:
+		return c.From.AutoIncrement || c.To.AutoIncrement
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package diff
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/shogo82148/schemalex-deploy/model"
+)
+
+// convertTableCharset converts the character columns at once, if the default character set
+// or the default collation of the table is changed and the columns follow it.
+// The columns that don't follow the new default are changed by alterTableColumns after the conversion.
+func (ctx *alterCtx) convertTableCharset() error {
+	_, ignoreCharset := ctx.ignoredTableOptions[tableOptionCharset]
+	_, ignoreCollation := ctx.ignoredTableOptions[tableOptionCollation]
+	if ignoreCharset || ignoreCollation {
+		return nil
+	}
+	if equalCharset(ctx.from.Charset(), ctx.to.Charset()) && equalCollation(effectiveCollation(ctx.from), effectiveCollation(ctx.to)) {
+		return nil
+	}
+
+	// convert the table only if some existing columns need it.
+	var needed bool
+	for _, to := range ctx.to.Columns {
+		from, ok := ctx.from.LookupColumn(to.ID())
+		if !ok || !from.Type.IsText() || !followsTableCharset(ctx.to, to) {
+			continue
+		}
+		if !equalCharset(ctx.from.ColumnCharset(from), ctx.to.Charset()) ||
+			!equalCollation(columnCollation(ctx.from, from), effectiveCollation(ctx.to)) {
+			needed = true
+			break
+		}
+	}
+	if !needed {
+		return nil
+	}
+
+	clause := &ConvertCharset{Charset: ctx.to.Charset()}
+	if opt, ok := ctx.to.LookupOption(tableOptionCollation); ok {
+		clause.Collation = model.Ident(opt.Value)
+	}
+	ctx.add(clause)
+	ctx.charsetConverted = true
+	return nil
+}
+
+// convertedColumn returns the column in the old schema after CONVERT TO CHARACTER SET,
+// described in the same way as the column in the new schema if it follows the new default.
+func (ctx *alterCtx) convertedColumn(from, to *model.TableColumn) *model.TableColumn {
+	if !from.Type.IsText() {
+		return from
+	}
+	col := from.Clone()
+	col.Type = promotedTextType(from.Type, ctx.from.ColumnCharset(from), ctx.to.Charset())
+	if followsTableCharset(ctx.to, to) {
+		col.CharacterSet = to.CharacterSet
+		col.Collation = to.Collation
+	} else {
+		col.CharacterSet = model.MaybeIdent{}
+		col.Collation = model.MaybeIdent{}
+	}
+	return col
+}
+
+// textTypes is the TEXT types in the order of their capacities.
+var textTypes = []model.ColumnType{
+	model.ColumnTypeTinyText,
+	model.ColumnTypeText,
+	model.ColumnTypeMediumText,
+	model.ColumnTypeLongText,
+}
+
+// promotedTextType returns the type that MySQL converts the TEXT type to,
+// so that the column can hold as many characters as before in the new character set.
+func promotedTextType(typ model.ColumnType, fromCharset, toCharset model.Ident) model.ColumnType {
+	i := slices.Index(textTypes, typ.SynonymType())
+	if i < 0 {
+		return typ
+	}
+	required := stringCapacities[textTypes[i]] / model.CharsetMaxLen(fromCharset) * model.CharsetMaxLen(toCharset)
+	for _, t := range textTypes[i:] {
+		if stringCapacities[t] >= required {
+			if t == textTypes[i] {
+				return typ
+			}
+			return t
+		}
+	}
+	return model.ColumnTypeLongText
+}
+
+// followsTableCharset reports whether the character column uses the default character set and collation of the table.
+func followsTableCharset(table *model.Table, col *model.TableColumn) bool {
+	return col.Type.IsText() &&
+		equalCharset(table.ColumnCharset(col), table.Charset()) &&
+		equalCollation(columnCollation(table, col), effectiveCollation(table))
+}
+
+// columnCollation returns the effective collation of the character column in the table.
+// It returns an empty string if it is unknown.
+func columnCollation(table *model.Table, col *model.TableColumn) string {
+	if col.Collation.Valid {
+		return string(col.Collation.Ident)
+	}
+	if col.CharacterSet.Valid && !equalCharset(col.CharacterSet.Ident, table.Charset()) {
+		return defaultCollations[strings.ToLower(string(col.CharacterSet.Ident))]
+	}
+	return effectiveCollation(table)
+}
+
+func equalCharset(a, b model.Ident) bool {
+	return strings.EqualFold(string(a), string(b))
+}
+
+func equalCollation(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
@@ -0,0 +1,115 @@
+package diff
+
+import (
+	"github.com/shogo82148/schemalex-deploy/model"
+)
+
+// findMovedColumns finds the existing columns whose positions are changed.
+// The columns that keep their order are the longest common subsequence of the columns in the both tables,
+// and the others are moved.
+func (ctx *alterCtx) findMovedColumns() error {
+	if !ctx.reorderColumns {
+		return nil
+	}
+
+	var from, to []string
+	for _, col := range ctx.from.Columns {
+		if ctx.toColumns.Contains(col.ID()) {
+			from = append(from, col.ID())
+		}
+	}
+	for _, col := range ctx.to.Columns {
+		if ctx.fromColumns.Contains(col.ID()) {
+			to = append(to, col.ID())
+		}
+	}
+
+	stay := longestCommonSubsequence(from, to)
+	for _, id := range to {
+		if !stay.Contains(id) {
+			ctx.movedColumns.Add(id)
+		}
+	}
+	return nil
+}
+
+// longestCommonSubsequence returns the longest common subsequence of a and b as a set.
+func longestCommonSubsequence(a, b []string) set {
+	// dp[i][j] is the length of the LCS of a[i:] and b[j:].
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else {
+				dp[i][j] = max(dp[i+1][j], dp[i][j+1])
+			}
+		}
+	}
+
+	ret := newSet()
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			ret.Add(a[i])
+			i++
+			j++
+		case dp[i+1][j] >= dp[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return ret
+}
+
+// writeColumnsInOrder adds the new columns and moves the existing columns in the order of the new table.
+// MySQL processes ADD COLUMN and CHANGE COLUMN ... AFTER in the order of the clauses,
+// so the columns that they refer to must be placed first.
+func (ctx *alterCtx) writeColumnsInOrder() error {
+	for _, col := range ctx.to.Columns {
+		switch {
+		case !ctx.fromColumns.Contains(col.ID()):
+			if err := ctx.writeAddColumn(col.ID()); err != nil {
+				return err
+			}
+		case ctx.movedColumns.Contains(col.ID()):
+			if err := ctx.writeMoveColumn(col); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeMoveColumn moves the column to its position in the new table.
+// The column is changed and renamed at the same time if necessary.
+func (ctx *alterCtx) writeMoveColumn(col *model.TableColumn) error {
+	before, ok := ctx.from.LookupColumn(col.ID())
+	if !ok {
+		return nil
+	}
+	ctx.add(&ChangeColumn{From: ctx.originalColumn(before), To: col, Position: ctx.columnPosition(col)})
+	return nil
+}
+
+// originalColumn returns the column in the old schema with its original name.
+// The old schema has the new names of the renamed columns. See (*diffCtx).renameColumns.
+func (ctx *alterCtx) originalColumn(col *model.TableColumn) *model.TableColumn {
+	oldName, renamed := ctx.renamedColumns[col.ID()]
+	if !renamed {
+		return col
+	}
+	col = col.Clone()
+	col.Name = oldName
+	return col
+}
+
+// columnPosition returns the position of the column in the new table.
+func (ctx *alterCtx) columnPosition(col *model.TableColumn) *ColumnPosition {
+	beforeCol, _ := ctx.to.LookupColumnBefore(col.ID())
+	return &ColumnPosition{After: beforeCol}
+}
@@ -0,0 +1,237 @@
+package diff
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/shogo82148/schemalex-deploy/model"
+)
+
+// DataLoss is the kind of a change of a column type, by whether the existing values survive it.
+type DataLoss int
+
+// List of the kinds of the changes of column types.
+const (
+	// DataLossless means the type is not changed, or it can hold exactly the same values.
+	DataLossless DataLoss = iota
+
+	// DataWidening means the new type can hold all the values of the old type, and more,
+	// e.g. VARCHAR(50) to VARCHAR(100) and INT to BIGINT.
+	DataWidening
+
+	// DataNarrowing means some values of the old type may not fit in the new type,
+	// e.g. VARCHAR(100) to VARCHAR(50) and BIGINT to INT.
+	DataNarrowing
+
+	// DataSignChange means the signedness of the integer is changed,
+	// and the negative values or the largest values are out of range.
+	DataSignChange
+
+	// DataCharsetChange means the values of the string column are converted to another character set,
+	// and the characters that the new character set doesn't have are lost.
+	DataCharsetChange
+)
+
+func (d DataLoss) String() string {
+	switch d {
+	case DataLossless:
+		return "lossless"
+	case DataWidening:
+		return "widening"
+	case DataNarrowing:
+		return "narrowing"
+	case DataSignChange:
+		return "sign change"
+	case DataCharsetChange:
+		return "charset change"
+	}
+	return "DataLoss(" + strconv.Itoa(int(d)) + ")"
+}
+
+// MayLoseData reports whether the change may lose or alter the existing values.
+func (d DataLoss) MayLoseData() bool {
+	return d >= DataNarrowing
+}
+
+// DataLoss classifies the change of the column type in the table.
+// The columns without the character set follow the default of the table.
+func (c *ChangeColumn) DataLoss(table *model.Table) DataLoss {
+	from, to := c.From, c.To
+	if from.Type.IsText() && to.Type.IsText() {
+		fromCharset, toCharset := table.ColumnCharset(from), table.ColumnCharset(to)
+		if !strings.EqualFold(string(fromCharset), string(toCharset)) {
+			return DataCharsetChange
+		}
+	}
+	return columnTypeDataLoss(table, from, to)
+}
+
+// integerRanks is the order of the integer types by their ranges.
+var integerRanks = map[model.ColumnType]int{
+	model.ColumnTypeTinyInt:   1,
+	model.ColumnTypeSmallInt:  2,
+	model.ColumnTypeMediumInt: 3,
+	model.ColumnTypeInt:       4,
+	model.ColumnTypeBigInt:    5,
+}
+
+// stringCapacities is the maximum lengths in bytes of the string types without the length.
+var stringCapacities = map[model.ColumnType]int{
+	model.ColumnTypeTinyText:   255,
+	model.ColumnTypeText:       65535,
+	model.ColumnTypeMediumText: 16777215,
+	model.ColumnTypeLongText:   4294967295,
+	model.ColumnTypeTinyBlob:   255,
+	model.ColumnTypeBlob:       65535,
+	model.ColumnTypeMediumBlob: 16777215,
+	model.ColumnTypeLongBlob:   4294967295,
+}
+
+// columnTypeDataLoss classifies the change of the type from the from column to the to column in the table.
+func columnTypeDataLoss(table *model.Table, from, to *model.TableColumn) DataLoss {
+	fromType, toType := from.Type.SynonymType(), to.Type.SynonymType()
+
+	// integers
+	fromRank, fromInt := integerRanks[fromType]
+	toRank, toInt := integerRanks[toType]
+	if fromInt && toInt {
+		switch {
+		case !from.Unsigned && to.Unsigned:
+			// negative values are lost.
+			return DataSignChange
+		case from.Unsigned && !to.Unsigned:
+			if toRank <= fromRank {
+				// the largest values are lost.
+				return DataSignChange
+			}
+			return DataWidening
+		}
+		return compareCapacity(fromRank, toRank)
+	}
+
+	// strings
+	if fromCap, ok := stringCapacity(table, from); ok {
+		toCap, ok := stringCapacity(table, to)
+		if !ok || fromType.IsText() != toType.IsText() {
+			return DataNarrowing
+		}
+		return compareCapacity(fromCap, toCap)
+	}
+
+	switch fromType {
+	case model.ColumnTypeDecimal:
+		if toType != model.ColumnTypeDecimal {
+			return DataNarrowing
+		}
+		if !from.Unsigned && to.Unsigned {
+			return DataSignChange
+		}
+		fromPrecision, fromScale := decimalPrecision(from)
+		toPrecision, toScale := decimalPrecision(to)
+		fromDigits, toDigits := fromPrecision-fromScale, toPrecision-toScale
+		switch {
+		case toScale < fromScale || toDigits < fromDigits:
+			return DataNarrowing
+		case toScale == fromScale && toDigits == fromDigits:
+			return DataLossless
+		}
+		return DataWidening
+	case model.ColumnTypeFloat:
+		if toType != model.ColumnTypeFloat && toType != model.ColumnTypeDouble {
+			return DataNarrowing
+		}
+		if !from.Unsigned && to.Unsigned {
+			return DataSignChange
+		}
+		if toType == model.ColumnTypeDouble {
+			return DataWidening
+		}
+		return DataLossless
+	case model.ColumnTypeEnum:
+		if toType != model.ColumnTypeEnum || !containsAll(to.EnumValues, from.EnumValues) {
+			return DataNarrowing
+		}
+		return compareCapacity(len(from.EnumValues), len(to.EnumValues))
+	case model.ColumnTypeSet:
+		if toType != model.ColumnTypeSet || !containsAll(to.SetValues, from.SetValues) {
+			return DataNarrowing
+		}
+		return compareCapacity(len(from.SetValues), len(to.SetValues))
+	case model.ColumnTypeDate:
+		switch toType {
+		case model.ColumnTypeDate:
+			return DataLossless
+		case model.ColumnTypeDateTime:
+			return DataWidening
+		}
+		return DataNarrowing
+	case model.ColumnTypeTimestamp:
+		switch toType {
+		case model.ColumnTypeTimestamp:
+		case model.ColumnTypeDateTime:
+			// DATETIME has a wider range than TIMESTAMP.
+			if lengthOf(to, 0) < lengthOf(from, 0) {
+				return DataNarrowing
+			}
+			return DataWidening
+		default:
+			return DataNarrowing
+		}
+	}
+	if fromType != toType {
+		return DataNarrowing
+	}
+	if !from.Unsigned && to.Unsigned {
+		return DataSignChange
+	}
+	return compareCapacity(lengthOf(from, 0), lengthOf(to, 0))
+}
+
+// compareCapacity classifies the change of the capacity of the type.
+func compareCapacity(from, to int) DataLoss {
+	switch {
+	case to < from:
+		return DataNarrowing
+	case to > from:
+		return DataWidening
+	}
+	return DataLossless
+}
+
+// stringCapacity returns the maximum length in bytes of the string column.
+// The lengths of CHAR and VARCHAR are in characters, so they are converted to bytes
+// to be compared with the lengths of TEXT types.
+func stringCapacity(table *model.Table, col *model.TableColumn) (int, bool) {
+	switch col.Type.SynonymType() {
+	case model.ColumnTypeChar:
+		return lengthOf(col, 1) * model.CharsetMaxLen(table.ColumnCharset(col)), true
+	case model.ColumnTypeVarChar:
+		return lengthOf(col, 0) * model.CharsetMaxLen(table.ColumnCharset(col)), true
+	case model.ColumnTypeBinary:
+		return lengthOf(col, 1), true
+	case model.ColumnTypeVarBinary:
+		return lengthOf(col, 0), true
+	}
+	capacity, ok := stringCapacities[col.Type.SynonymType()]
+	return capacity, ok
+}
+
+// decimalPrecision returns the precision and the scale of the DECIMAL column.
+func decimalPrecision(col *model.TableColumn) (precision, scale int) {
+	precision = lengthOf(col, 10)
+	if col.Length != nil && col.Length.Decimals.Valid {
+		scale, _ = strconv.Atoi(col.Length.Decimals.Value)
+	}
+	return precision, scale
+}
+
+// containsAll reports whether s contains all the values of t.
+func containsAll(s, t []string) bool {
+	for _, v := range t {
+		if !slices.Contains(s, v) {
+			return false
+		}
+	}
+	return true
+}
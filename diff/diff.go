@@ -3,15 +3,14 @@
 package diff
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
 	"io"
-	"reflect"
+	"slices"
 	"sort"
 	"strings"
 
 	"github.com/shogo82148/schemalex-deploy"
-	"github.com/shogo82148/schemalex-deploy/format"
 	"github.com/shogo82148/schemalex-deploy/model"
 )
 
@@ -23,6 +22,25 @@ type diffCtx struct {
 	cur     model.Stmts
 	result  Stmts
 	indent  string
+
+	// droppedForeignKeys is the set of the foreign keys (table ID -> index IDs)
+	// that have already been dropped before the tables are altered.
+	droppedForeignKeys map[string]set
+
+	// modifiedColumns is the set of the columns that change their types (table ID -> column IDs).
+	modifiedColumns map[string]set
+
+	// ignoredTableOptions is the set of the normalized keys of the table options that are not compared.
+	ignoredTableOptions set
+
+	// renamedColumns is the old names of the renamed columns (table ID -> new column ID -> old name).
+	renamedColumns map[string]map[string]model.Ident
+
+	// noColumnReordering disables reordering of the existing columns.
+	noColumnReordering bool
+
+	// columnReordering overrides noColumnReordering for each table (table ID -> enabled).
+	columnReordering map[string]bool
 }
 
 func newDiffCtx(from, to, cur model.Stmts) *diffCtx {
@@ -40,16 +58,20 @@ func newDiffCtx(from, to, cur model.Stmts) *diffCtx {
 	}
 
 	return &diffCtx{
-		fromSet: fromSet,
-		toSet:   toSet,
-		from:    from,
-		to:      to,
-		cur:     cur,
+		fromSet:             fromSet,
+		toSet:               toSet,
+		from:                from,
+		to:                  to,
+		cur:                 cur,
+		droppedForeignKeys:  make(map[string]set),
+		modifiedColumns:     make(map[string]set),
+		ignoredTableOptions: newSet(),
+		renamedColumns:      make(map[string]map[string]model.Ident),
 	}
 }
 
-func (ctx *diffCtx) append(stmt string) {
-	ctx.result.Append(Stmt(stmt))
+func (ctx *diffCtx) append(stmt Stmt) {
+	ctx.result.Append(stmt)
 }
 
 // Diff compares two model.Stmts, and generates a series of
@@ -75,15 +97,48 @@ func Diff(from, to model.Stmts, options ...Option) (Stmts, error) {
 			return nil, err
 		}
 	}
+	if opts.ignore != nil {
+		var err error
+		if from, err = opts.ignore.Filter(from); err != nil {
+			return nil, err
+		}
+		if to, err = opts.ignore.Filter(to); err != nil {
+			return nil, err
+		}
+		if cur, err = opts.ignore.Filter(cur); err != nil {
+			return nil, err
+		}
+	}
 	ctx := newDiffCtx(from, to, cur)
 	ctx.indent = opts.indent
+	ignored := opts.ignoredTableOptions
+	if ignored == nil {
+		ignored = DefaultIgnoredTableOptions
+	}
+	for _, key := range ignored {
+		ctx.ignoredTableOptions.Add(normalizeTableOptionKey(key))
+	}
+	ctx.noColumnReordering = opts.noColumnReordering
+	ctx.columnReordering = opts.columnReordering
+	version := latestServerVersion
+	if opts.onlineDDL != "" {
+		var err error
+		version, err = ParseServerVersion(opts.onlineDDL)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	if txn {
-		ctx.append(`BEGIN`)
-		ctx.append(`SET FOREIGN_KEY_CHECKS = 0`)
+		ctx.append(RawStmt(`BEGIN`))
+		ctx.append(RawStmt(`SET FOREIGN_KEY_CHECKS = 0`))
 	}
 
 	procs := []func() error{
+		ctx.renameTables,
+		ctx.renameColumns,
+		ctx.dropForeignKeys,
+		ctx.dropModifiedForeignKeys,
 		ctx.dropTables,
 		ctx.createTables,
 		ctx.alterTables,
@@ -95,8 +150,39 @@ func Diff(from, to model.Stmts, options ...Option) (Stmts, error) {
 	}
 
 	if txn {
-		ctx.append(`SET FOREIGN_KEY_CHECKS = 1`)
-		ctx.append(`COMMIT`)
+		ctx.append(RawStmt(`SET FOREIGN_KEY_CHECKS = 1`))
+		ctx.append(RawStmt(`COMMIT`))
+	}
+
+	if opts.alterBatching != BatchAll {
+		var result Stmts
+		for _, stmt := range ctx.result {
+			if alter, ok := stmt.(*AlterTable); ok {
+				for _, s := range splitAlterTable(alter, opts.alterBatching, version) {
+					result = append(result, s)
+				}
+				continue
+			}
+			result = append(result, stmt)
+		}
+		ctx.result = result
+	}
+
+	if opts.onlineDDL != "" {
+		for _, stmt := range ctx.result {
+			if stmt, ok := stmt.(*AlterTable); ok {
+				stmt.OnlineDDL = stmt.PredictOnlineDDL(version)
+			}
+		}
+	}
+
+	// report the errors in formatting the models.
+	for _, stmt := range ctx.result {
+		if r, ok := stmt.(renderer); ok {
+			if err := r.render(&strings.Builder{}); err != nil {
+				return nil, fmt.Errorf("failed to format a statement: %w", err)
+			}
+		}
 	}
 
 	return ctx.result, nil
@@ -140,43 +226,374 @@ func Strings(dst io.Writer, from, to string, options ...Option) error {
 	return Statements(dst, stmts1, stmts2, options...)
 }
 
-func (ctx *diffCtx) dropTables() error {
-	ids := ctx.fromSet.Difference(ctx.toSet)
+// Equal reports whether the two schemas are equivalent after normalization,
+// that is, no statement is needed to migrate a to b.
+// If they are not, the explanation is the statements to migrate a to b.
+// It returns an error if the migration can't be planned, e.g. the annotations are wrong.
+func Equal(a, b model.Stmts) (bool, string, error) {
+	stmts, err := Diff(a, b, WithTransaction(false))
+	if err != nil {
+		return false, "", err
+	}
+	if len(stmts) == 0 {
+		return true, "", nil
+	}
+	var buf strings.Builder
+	if _, err := stmts.WriteTo(&buf); err != nil {
+		return false, "", err
+	}
+	return false, buf.String(), nil
+}
+
+// lookupTables looks up the tables with the IDs from stmts.
+func lookupTables(stmts model.Stmts, ids set) ([]*model.Table, error) {
+	tables := make([]*model.Table, 0, ids.Cardinality())
 	for _, id := range ids.ToSlice() {
-		stmt, ok := ctx.from.Lookup(id)
+		stmt, ok := stmts.Lookup(id)
 		if !ok {
-			return fmt.Errorf("failed to lookup table: %q", id)
+			return nil, fmt.Errorf("failed to lookup table: %q", id)
+		}
+
+		table, ok := stmt.(*model.Table)
+		if !ok {
+			return nil, fmt.Errorf(`lookup failed: %q is not a model.Table`, id)
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+// renameTables renames the tables that have the "renamed-from" annotation.
+// After renaming, the old schema is rewritten as if the tables have had the new names,
+// so that the following procedures compare the tables with same names.
+func (ctx *diffCtx) renameTables() error {
+	renames := make(map[string]model.Ident) // old table ID -> new name
+	for _, stmt := range ctx.to {
+		table, ok := stmt.(*model.Table)
+		if !ok || !table.RenamedFrom.Valid {
+			continue
 		}
+		oldID := model.NewTable(table.RenamedFrom.Ident).ID()
+		if ctx.fromSet.Contains(table.ID()) || !ctx.fromSet.Contains(oldID) || ctx.toSet.Contains(oldID) {
+			// the table has already been renamed, or the old table is still used.
+			continue
+		}
+		if _, ok := renames[oldID]; ok {
+			return fmt.Errorf("table %s is renamed to more than one table", table.RenamedFrom.Ident.Quoted())
+		}
+		renames[oldID] = table.Name
+	}
+	if len(renames) == 0 {
+		return nil
+	}
 
+	from := make(model.Stmts, 0, len(ctx.from))
+	for _, stmt := range ctx.from {
 		table, ok := stmt.(*model.Table)
 		if !ok {
-			return fmt.Errorf(`lookup failed: %q is not a model.Table`, id)
+			from = append(from, stmt)
+			continue
 		}
-		ctx.append("DROP TABLE " + table.Name.Quoted())
+		if name, ok := renames[table.ID()]; ok {
+			ctx.append(&RenameTable{Table: table, Name: name})
+			ctx.fromSet.Remove(table.ID())
+			table = renameTable(table, name)
+			ctx.fromSet.Add(table.ID())
+		}
+		from = append(from, renameReferences(table, renames))
 	}
+	ctx.from = from
 	return nil
 }
 
-func (ctx *diffCtx) createTables() error {
-	var buf bytes.Buffer
+// renameTable returns a copy of the table with the new name.
+func renameTable(table *model.Table, name model.Ident) *model.Table {
+	newTable := table.Clone()
+	newTable.Name = name
+	for _, col := range newTable.Columns {
+		if col.TableID != "" {
+			col.TableID = newTable.ID()
+		}
+	}
+	for _, idx := range newTable.Indexes {
+		if idx.Table != "" {
+			idx.Table = newTable.ID()
+		}
+	}
+	return newTable
+}
 
-	ids := ctx.toSet.Difference(ctx.fromSet)
-	for _, id := range ids.ToSlice() {
-		// Lookup the corresponding statement, and add its SQL
-		stmt, ok := ctx.to.Lookup(id)
+// renameReferences rewrites the foreign keys that refer the renamed tables,
+// in the same way as MySQL does on RENAME TABLE.
+func renameReferences(table *model.Table, renames map[string]model.Ident) *model.Table {
+	var newTable *model.Table
+	for i, fk := range table.Indexes {
+		if fk.Kind != model.IndexKindForeignKey || fk.Reference == nil {
+			continue
+		}
+		name, ok := renames[fk.Reference.TableID()]
+		if !ok {
+			continue
+		}
+		if newTable == nil {
+			newTable = table.Clone()
+		}
+		newTable.Indexes[i].Reference.TableName = name
+	}
+	if newTable == nil {
+		return table
+	}
+	return newTable
+}
+
+// renameColumns finds the columns that have the "renamed-from" annotation,
+// and rewrites the old schema as if the columns have had the new names.
+// The indexes and the foreign keys that refer the columns are also rewritten in the same way as MySQL does.
+// alterTables renames the columns actually.
+func (ctx *diffCtx) renameColumns() error {
+	renames := make(map[string]map[string]model.Ident) // table ID -> old column ID -> new name
+	for _, id := range ctx.toSet.Intersect(ctx.fromSet).ToSlice() {
+		stmt, _ := ctx.from.Lookup(id)
+		from := stmt.(*model.Table)
+		stmt, _ = ctx.to.Lookup(id)
+		to := stmt.(*model.Table)
+
+		for _, col := range to.Columns {
+			if !col.RenamedFrom.Valid {
+				continue
+			}
+			oldID := model.NewTableColumn(string(col.RenamedFrom.Ident)).ID()
+			if _, ok := from.LookupColumn(col.ID()); ok {
+				// the column has already been renamed.
+				continue
+			}
+			if _, ok := from.LookupColumn(oldID); !ok {
+				// the annotation may be bound to a wrong column, and the data would be lost.
+				return fmt.Errorf("column %s of table %s is renamed from %s, but the old schema doesn't have it", col.Name.Quoted(), to.Name.Quoted(), col.RenamedFrom.Ident.Quoted())
+			}
+			if _, ok := to.LookupColumn(oldID); ok {
+				// the old column is still used.
+				continue
+			}
+			if renames[id] == nil {
+				renames[id] = make(map[string]model.Ident)
+				ctx.renamedColumns[id] = make(map[string]model.Ident)
+			}
+			if _, ok := renames[id][oldID]; ok {
+				return fmt.Errorf("column %s of table %s is renamed to more than one column", col.RenamedFrom.Ident.Quoted(), to.Name.Quoted())
+			}
+			renames[id][oldID] = col.Name
+			ctx.renamedColumns[id][col.ID()] = col.RenamedFrom.Ident
+		}
+	}
+	if len(renames) == 0 {
+		return nil
+	}
+
+	from := make(model.Stmts, 0, len(ctx.from))
+	for _, stmt := range ctx.from {
+		if table, ok := stmt.(*model.Table); ok {
+			stmt = renameColumns(table, renames)
+		}
+		from = append(from, stmt)
+	}
+	ctx.from = from
+	return nil
+}
+
+// renameColumns returns a copy of the table whose columns are renamed.
+func renameColumns(table *model.Table, renames map[string]map[string]model.Ident) *model.Table {
+	rename := func(cols map[string]model.Ident, name *model.Ident) {
+		if newName, ok := cols[model.NewTableColumn(string(*name)).ID()]; ok {
+			*name = newName
+		}
+	}
+
+	newTable := table.Clone()
+	if cols, ok := renames[table.ID()]; ok {
+		for _, col := range newTable.Columns {
+			rename(cols, &col.Name)
+		}
+		for _, idx := range newTable.Indexes {
+			for _, col := range idx.Columns {
+				rename(cols, &col.Name)
+			}
+		}
+	}
+	for _, fk := range newTable.ForeignKeys() {
+		if cols, ok := renames[fk.Reference.TableID()]; ok {
+			for _, col := range fk.Reference.Columns {
+				rename(cols, &col.Name)
+			}
+		}
+	}
+	return newTable
+}
+
+// dropForeignKeys drops the foreign keys that refer the tables to be dropped.
+// They must be dropped before the tables.
+func (ctx *diffCtx) dropForeignKeys() error {
+	dropped := ctx.fromSet.Difference(ctx.toSet)
+	if dropped.Cardinality() == 0 {
+		return nil
+	}
+
+	tables, err := lookupTables(ctx.from, ctx.toSet.Intersect(ctx.fromSet))
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		stmt, ok := ctx.to.Lookup(table.ID())
 		if !ok {
-			return fmt.Errorf("failed to lookup table: %q", id)
+			return fmt.Errorf("table not found in new schema: %q", table.ID())
+		}
+		// name the unnamed foreign keys as MySQL does, so that we can drop them.
+		table = table.NameIndexes()
+		after := stmt.(*model.Table).NameIndexes()
+
+		var clauses []AlterClause
+		for _, fk := range table.ForeignKeys() {
+			if _, ok := dropped[fk.Reference.TableID()]; !ok {
+				continue
+			}
+			if _, ok := after.LookupIndex(fk.ID()); ok {
+				continue
+			}
+			name := getIndexName(fk)
+			if !name.Valid {
+				return fmt.Errorf("can not drop foreign key without name: %q", fk.ID())
+			}
+
+			clauses = append(clauses, &DropForeignKey{Index: fk, Name: name.Ident})
+
+			fks, ok := ctx.droppedForeignKeys[table.ID()]
+			if !ok {
+				fks = newSet()
+				ctx.droppedForeignKeys[table.ID()] = fks
+			}
+			fks.Add(fk.ID())
+		}
+		if len(clauses) > 0 {
+			ctx.append(&AlterTable{Table: table, Clauses: clauses})
+		}
+	}
+	return nil
+}
+
+func (ctx *diffCtx) dropTables() error {
+	tables, err := lookupTables(ctx.from, ctx.fromSet.Difference(ctx.toSet))
+	if err != nil {
+		return err
+	}
+
+	// drop the referring tables first.
+	tables, err = model.SortTables(tables)
+	var cycle *model.CycleError
+	if err != nil && !errors.As(err, &cycle) {
+		return err
+	}
+	slices.Reverse(tables)
+
+	// If the foreign keys make a cycle, some of them refer tables that are dropped earlier.
+	// We drop them before all tables are dropped.
+	if cycle != nil {
+		dropped := newSet()
+		for _, table := range tables {
+			stmt, err := dropBackwardReferences(table, dropped)
+			if err != nil {
+				return err
+			}
+			if stmt != nil {
+				ctx.append(stmt)
+			}
+			dropped.Add(table.ID())
 		}
+	}
+
+	for _, table := range tables {
+		ctx.append(&DropTable{Table: table})
+	}
+	return nil
+}
 
-		buf.Reset()
-		if err := format.SQL(&buf, stmt, format.WithIndent(ctx.indent, 1)); err != nil {
-			return fmt.Errorf("failed to format a statement: %w", err)
+// dropBackwardReferences returns an ALTER TABLE statement to drop the foreign keys that refer the dropped tables,
+// or nil if there is no such foreign key.
+func dropBackwardReferences(table *model.Table, dropped set) (Stmt, error) {
+	// name the unnamed foreign keys as MySQL does, so that we can drop them.
+	table = table.NameIndexes()
+
+	var clauses []AlterClause
+	for _, fk := range table.ForeignKeys() {
+		if _, ok := dropped[fk.Reference.TableID()]; !ok {
+			continue
 		}
-		ctx.append(buf.String())
+		name := getIndexName(fk)
+		if !name.Valid {
+			return nil, fmt.Errorf("can not drop foreign key without name: %q", fk.ID())
+		}
+		clauses = append(clauses, &DropForeignKey{Index: fk, Name: name.Ident})
+	}
+	if len(clauses) == 0 {
+		return nil, nil
+	}
+	return &AlterTable{Table: table, Clauses: clauses}, nil
+}
+
+func (ctx *diffCtx) createTables() error {
+	pending := ctx.toSet.Difference(ctx.fromSet)
+	tables, err := lookupTables(ctx.to, pending)
+	if err != nil {
+		return err
+	}
+
+	// create the referred tables first.
+	tables, err = model.SortTables(tables)
+	var cycle *model.CycleError
+	if err != nil && !errors.As(err, &cycle) {
+		return err
+	}
+
+	// If the foreign keys make a cycle, some of them refer tables that are not created yet.
+	// We add them after all tables are created.
+	var lazy []Stmt
+	for _, table := range tables {
+		if cycle != nil {
+			table, lazy, err = splitForwardReferences(table, pending, lazy)
+			if err != nil {
+				return err
+			}
+		}
+		delete(pending, table.ID())
+
+		ctx.append(&CreateTable{Table: table, Indent: ctx.indent})
+	}
+	for _, stmt := range lazy {
+		ctx.append(stmt)
 	}
 	return nil
 }
 
+// splitForwardReferences removes the foreign keys that refer the pending tables from the table,
+// and appends ALTER TABLE statements to add them to lazy.
+func splitForwardReferences(table *model.Table, pending set, lazy []Stmt) (*model.Table, []Stmt, error) {
+	newTable := *table
+	newTable.Indexes = make([]*model.Index, 0, len(table.Indexes))
+	for _, idx := range table.Indexes {
+		if idx.Kind == model.IndexKindForeignKey && idx.Reference != nil {
+			ref := idx.Reference.TableID()
+			if _, ok := pending[ref]; ok && ref != table.ID() {
+				lazy = append(lazy, &AlterTable{
+					Table:   table,
+					Clauses: []AlterClause{&AddIndex{Index: idx}},
+				})
+				continue
+			}
+		}
+		newTable.Indexes = append(newTable.Indexes, idx)
+	}
+	return &newTable, lazy, nil
+}
+
 type alterCtx struct {
 	fromColumns set
 	toColumns   set
@@ -184,24 +601,67 @@ type alterCtx struct {
 	toIndexes   set
 	from        *model.Table
 	to          *model.Table
-	buf         strings.Builder
+	clauses     []AlterClause
 
 	// cur is the current model deployed to MySQL actually.
 	// it may be nil.
 	cur *model.Table
+
+	// droppedForeignKeys is the set of the foreign keys that have already been dropped.
+	droppedForeignKeys set
+
+	// modifiedColumns is the set of the columns that change their types (table ID -> column IDs).
+	modifiedColumns map[string]set
+
+	// pendingTables is the set of the tables that are altered after this table.
+	pendingTables set
+
+	// lazyForeignKeys is the foreign keys that are added after all tables are altered,
+	// because their referenced columns are changed after this table.
+	lazyForeignKeys []*model.Index
+
+	// ignoredTableOptions is the set of the table options that are not compared.
+	ignoredTableOptions set
+
+	// renamedColumns is the old names of the renamed columns (column ID -> old name).
+	renamedColumns map[string]model.Ident
+
+	// renamedIndexes is the pairs of the old index and the new one that are renamed.
+	renamedIndexes [][2]*model.Index
+
+	// reorderColumns reports whether the existing columns are reordered.
+	reorderColumns bool
+
+	// movedColumns is the set of the IDs of the existing columns that change their positions.
+	movedColumns set
+
+	// charsetConverted reports whether the character columns are converted by CONVERT TO CHARACTER SET.
+	charsetConverted bool
 }
 
 func (ctx *diffCtx) alterTables() error {
 	procs := []func(*alterCtx) error{
+		(*alterCtx).findRenamedIndexes,
 		(*alterCtx).dropTableIndexes,
+		(*alterCtx).renameTableIndexes,
 		(*alterCtx).dropTableColumns,
+		(*alterCtx).convertTableCharset,
+		(*alterCtx).findMovedColumns,
 		(*alterCtx).addTableColumns,
 		(*alterCtx).alterTableColumns,
 		(*alterCtx).addTableIndexes,
+		(*alterCtx).alterTableOptions,
+		(*alterCtx).orderAutoIncrementChanges,
 	}
 
 	ids := ctx.toSet.Intersect(ctx.fromSet)
+	pending := newSet()
+	for id := range ids {
+		pending.Add(id)
+	}
+	var lazy []Stmt
 	for _, id := range ids.ToSlice() {
+		pending.Remove(id)
 		var stmt model.Stmt
 		var ok bool
 
@@ -210,14 +670,15 @@ func (ctx *diffCtx) alterTables() error {
 		if !ok {
 			return fmt.Errorf("table not found in old schema (alter table): %q", id)
 		}
-		beforeStmt := stmt.(*model.Table)
+		// name the unnamed indexes as MySQL does, so that we can compare and drop them.
+		beforeStmt := stmt.(*model.Table).NameIndexes()
 
 		// after statement
 		stmt, ok = ctx.to.Lookup(id)
 		if !ok {
 			return fmt.Errorf("table not found in new schema (alter table): %q", id)
 		}
-		afterStmt := stmt.(*model.Table)
+		afterStmt := stmt.(*model.Table).NameIndexes()
 
 		// current statement
 		var curStmt *model.Table
@@ -229,16 +690,28 @@ func (ctx *diffCtx) alterTables() error {
 		}
 
 		alterCtx := newAlterCtx(ctx, beforeStmt, afterStmt, curStmt)
+		alterCtx.pendingTables = pending
 		for _, p := range procs {
 			if err := p(alterCtx); err != nil {
 				return fmt.Errorf("failed to generate alter table %q: %w", id, err)
 			}
 		}
-		if alterCtx.buf.Len() > 0 {
-			ctx.result = append(ctx.result, Stmt(alterCtx.buf.String()))
+		if len(alterCtx.clauses) > 0 {
+			ctx.append(&AlterTable{Table: afterStmt, Clauses: alterCtx.clauses})
+		}
+		if len(alterCtx.lazyForeignKeys) > 0 {
+			clauses := make([]AlterClause, 0, len(alterCtx.lazyForeignKeys))
+			for _, fk := range alterCtx.lazyForeignKeys {
+				clauses = append(clauses, &AddIndex{Index: fk})
+			}
+			lazy = append(lazy, &AlterTable{Table: afterStmt, Clauses: clauses})
 		}
 	}
 
+	// add the foreign keys after their referenced columns are changed.
+	for _, stmt := range lazy {
+		ctx.append(stmt)
+	}
 	return nil
 }
 
@@ -271,44 +744,48 @@ func newAlterCtx(ctx *diffCtx, from, to, cur *model.Table) *alterCtx {
 		from:        from,
 		to:          to,
 		cur:         cur,
-	}
-}
 
-// begin begins a new alter specification.
-func (ctx *alterCtx) begin() {
-	if ctx.buf.Len() == 0 {
-		ctx.writeString("ALTER TABLE ")
-		ctx.writeIdent(ctx.from.Name)
-		ctx.writeString(" ")
-	} else {
-		ctx.writeString(", ")
+		droppedForeignKeys:  ctx.droppedForeignKeys[from.ID()],
+		modifiedColumns:     ctx.modifiedColumns,
+		ignoredTableOptions: ctx.ignoredTableOptions,
+		renamedColumns:      ctx.renamedColumns[from.ID()],
+		reorderColumns:      ctx.reorderColumns(to),
+		movedColumns:        newSet(),
 	}
 }
 
-func (ctx *alterCtx) writeString(s string) {
-	ctx.buf.WriteString(s)
+// reorderColumns returns whether the existing columns of the table are reordered.
+func (ctx *diffCtx) reorderColumns(table *model.Table) bool {
+	if enabled, ok := ctx.columnReordering[table.ID()]; ok {
+		return enabled
+	}
+	return !ctx.noColumnReordering
 }
 
-func (ctx *alterCtx) writeIdent(ident model.Ident) {
-	ctx.buf.WriteString(ident.Quoted())
+// add appends a new alter specification.
+func (ctx *alterCtx) add(clause AlterClause) {
+	ctx.clauses = append(ctx.clauses, clause)
 }
 
 func (ctx *alterCtx) dropTableColumns() error {
 	columnNames := ctx.fromColumns.Difference(ctx.toColumns)
 
 	for _, columnName := range columnNames.ToSlice() {
-		ctx.begin()
-		ctx.writeString("DROP COLUMN ")
 		col, ok := ctx.from.LookupColumn(columnName)
 		if !ok {
 			return fmt.Errorf("failed to lookup column %q", columnName)
 		}
-		ctx.writeIdent(col.Name)
+		ctx.add(&DropColumn{Column: col})
 	}
 	return nil
 }
 
 func (ctx *alterCtx) addTableColumns() error {
+	if ctx.movedColumns.Cardinality() > 0 {
+		// the new columns may be placed after the moved columns.
+		return ctx.writeColumnsInOrder()
+	}
+
 	beforeToNext := make(map[string]string) // lookup next column
 	nextToBefore := make(map[string]string) // lookup before column
 
@@ -384,19 +861,7 @@ func (ctx *alterCtx) writeAddColumn(columnNames ...string) error {
 			return fmt.Errorf("failed to lookup column %q", columnName)
 		}
 
-		beforeCol, hasBeforeCol := ctx.to.LookupColumnBefore(stmt.ID())
-		ctx.begin()
-		ctx.writeString("ADD COLUMN ")
-		if err := format.SQL(&ctx.buf, stmt); err != nil {
-			return err
-		}
-
-		if hasBeforeCol {
-			ctx.writeString(" AFTER ")
-			ctx.writeIdent(beforeCol.Name)
-		} else {
-			ctx.writeString(" FIRST")
-		}
+		ctx.add(&AddColumn{Column: stmt, Position: ctx.columnPosition(stmt)})
 	}
 	return nil
 }
@@ -404,6 +869,10 @@ func (ctx *alterCtx) writeAddColumn(columnNames ...string) error {
 func (ctx *alterCtx) alterTableColumns() error {
 	columnNames := ctx.toColumns.Intersect(ctx.fromColumns)
 	for _, columnName := range columnNames.ToSlice() {
+		if ctx.movedColumns.Contains(columnName) {
+			// already changed with its new position.
+			continue
+		}
 		beforeColumnStmt, ok := ctx.from.LookupColumn(columnName)
 		if !ok {
 			return fmt.Errorf("column not found in old schema: %q", columnName)
@@ -414,17 +883,83 @@ func (ctx *alterCtx) alterTableColumns() error {
 			return fmt.Errorf("column not found in new schema: %q", columnName)
 		}
 
-		if reflect.DeepEqual(beforeColumnStmt, afterColumnStmt) {
+		_, renamed := ctx.renamedColumns[columnName]
+		converted := beforeColumnStmt
+		if ctx.charsetConverted {
+			converted = ctx.convertedColumn(beforeColumnStmt, afterColumnStmt)
+		}
+		if converted.Equal(afterColumnStmt) {
+			if renamed {
+				ctx.add(&RenameColumn{From: ctx.originalColumn(beforeColumnStmt), To: afterColumnStmt})
+			}
+			continue
+		}
+		ctx.add(&ChangeColumn{From: ctx.originalColumn(beforeColumnStmt), To: afterColumnStmt})
+	}
+	return nil
+}
+
+// lookupIndexName returns the name of the index in the old schema, that is used to drop or rename it.
+func (ctx *alterCtx) lookupIndexName(indexStmt *model.Index) (model.Ident, error) {
+	indexName := getIndexName(indexStmt)
+	if ctx.cur != nil && !hasIndexName(ctx.cur, indexName) {
+		// the index may have been named differently, e.g. it was added by ALTER TABLE.
+		// guess the name from the current schema.
+		return ctx.guessDropTableIndexName(indexStmt)
+	}
+	if !indexName.Valid {
+		return "", fmt.Errorf("can not drop index without name: %q", indexStmt.ID())
+	}
+	return indexName.Ident, nil
+}
+
+// findRenamedIndexes finds the indexes that keep their definitions but change their names,
+// so that they are renamed instead of dropped and added.
+func (ctx *alterCtx) findRenamedIndexes() error {
+	added := ctx.toIndexes.Difference(ctx.fromIndexes)
+	for _, index := range ctx.fromIndexes.Difference(ctx.toIndexes).ToSlice() {
+		if _, ok := ctx.droppedForeignKeys[index]; ok {
+			continue
+		}
+		from, ok := ctx.from.LookupIndex(index)
+		if !ok {
+			return fmt.Errorf("index not found in old schema: %q", index)
+		}
+		switch from.Kind {
+		case model.IndexKindPrimaryKey, model.IndexKindForeignKey:
+			// the primary key has no name, and foreign keys can't be renamed.
 			continue
 		}
 
-		ctx.begin()
-		ctx.writeString("CHANGE COLUMN ")
-		ctx.writeIdent(afterColumnStmt.Name)
-		ctx.writeString(" ")
-		if err := format.SQL(&ctx.buf, afterColumnStmt); err != nil {
+		for _, to := range ctx.to.Indexes {
+			if !added.Contains(to.ID()) || !equalIndex(from, to) || !slices.EqualFunc(from.Options, to.Options, (*model.IndexOption).Equal) {
+				continue
+			}
+			added.Remove(to.ID())
+			ctx.renamedIndexes = append(ctx.renamedIndexes, [2]*model.Index{from, to})
+			break
+		}
+	}
+	return nil
+}
+
+// isRenamedIndex returns whether the index is renamed.
+func (ctx *alterCtx) isRenamedIndex(id string) bool {
+	for _, pair := range ctx.renamedIndexes {
+		if pair[0].ID() == id || pair[1].ID() == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (ctx *alterCtx) renameTableIndexes() error {
+	for _, pair := range ctx.renamedIndexes {
+		name, err := ctx.lookupIndexName(pair[0])
+		if err != nil {
 			return err
 		}
+		ctx.add(&RenameIndex{From: pair[0], To: pair[1], Name: name})
 	}
 	return nil
 }
@@ -433,43 +968,40 @@ func (ctx *alterCtx) dropTableIndexes() error {
 	indexes := ctx.fromIndexes.Difference(ctx.toIndexes)
 	// drop index after drop constraint.
 	// because cannot drop index if needed in a foreign key constraint
-	lazy := make([]model.Ident, 0, indexes.Cardinality())
+	lazy := make([]*DropIndex, 0, indexes.Cardinality())
 	for _, index := range indexes.ToSlice() {
+		if _, ok := ctx.droppedForeignKeys[index]; ok {
+			continue
+		}
+		if ctx.isRenamedIndex(index) {
+			continue
+		}
+
 		indexStmt, ok := ctx.from.LookupIndex(index)
 		if !ok {
 			return fmt.Errorf("index not found in old schema: %q", index)
 		}
 
 		if indexStmt.Kind == model.IndexKindPrimaryKey {
-			ctx.begin()
-			ctx.writeString("DROP PRIMARY KEY")
+			ctx.add(&DropIndex{Index: indexStmt})
 			continue
 		}
 
-		indexName := getIndexName(indexStmt)
-		if !indexName.Valid {
-			name, err := ctx.guessDropTableIndexName(indexStmt)
-			if err != nil {
-				return err
-			}
-			indexName.Valid = true
-			indexName.Ident = name
+		indexName, err := ctx.lookupIndexName(indexStmt)
+		if err != nil {
+			return err
 		}
 		if indexStmt.Kind != model.IndexKindForeignKey {
-			lazy = append(lazy, indexName.Ident)
+			lazy = append(lazy, &DropIndex{Index: indexStmt, Name: indexName})
 			continue
 		}
 
-		ctx.begin()
-		ctx.writeString("DROP FOREIGN KEY ")
-		ctx.writeIdent(indexName.Ident)
+		ctx.add(&DropForeignKey{Index: indexStmt, Name: indexName})
 	}
 
 	// drop index after drop CONSTRAINT
-	for _, indexName := range lazy {
-		ctx.begin()
-		ctx.writeString("DROP INDEX ")
-		ctx.writeIdent(indexName)
+	for _, clause := range lazy {
+		ctx.add(clause)
 	}
 
 	return nil
@@ -477,10 +1009,19 @@ func (ctx *alterCtx) dropTableIndexes() error {
 
 func (ctx *alterCtx) addTableIndexes() error {
 	indexes := ctx.toIndexes.Difference(ctx.fromIndexes)
+	// add the foreign keys again that have been dropped before changing their columns.
+	for index := range ctx.droppedForeignKeys {
+		if ctx.toIndexes.Contains(index) {
+			indexes.Add(index)
+		}
+	}
 	// add index before add foreign key.
 	// because cannot add index if create implicitly index by foreign key.
 	lazy := make([]*model.Index, 0, indexes.Cardinality())
 	for _, index := range indexes.ToSlice() {
+		if ctx.isRenamedIndex(index) {
+			continue
+		}
 		indexStmt, ok := ctx.to.LookupIndex(index)
 		if !ok {
 			return fmt.Errorf("index not found in old schema: %q", index)
@@ -490,19 +1031,15 @@ func (ctx *alterCtx) addTableIndexes() error {
 			continue
 		}
 
-		ctx.begin()
-		ctx.writeString("ADD ")
-		if err := format.SQL(&ctx.buf, indexStmt); err != nil {
-			return err
-		}
+		ctx.add(&AddIndex{Index: indexStmt})
 	}
 
 	for _, indexStmt := range lazy {
-		ctx.begin()
-		ctx.writeString("ADD ")
-		if err := format.SQL(&ctx.buf, indexStmt); err != nil {
-			return err
+		if ctx.deferForeignKey(indexStmt) {
+			ctx.lazyForeignKeys = append(ctx.lazyForeignKeys, indexStmt)
+			continue
 		}
+		ctx.add(&AddIndex{Index: indexStmt})
 	}
 
 	return nil
@@ -540,7 +1077,12 @@ LOOP:
 	return "", fmt.Errorf("can not drop index without name: %q", indexStmt.ID())
 }
 
+// getIndexName returns the name to drop the index.
+// It is the constraint name for foreign keys, and the index name for the others.
 func getIndexName(idx *model.Index) model.MaybeIdent {
+	if idx.Kind == model.IndexKindForeignKey && idx.ConstraintName.Valid {
+		return idx.ConstraintName
+	}
 	if idx.Name.Valid {
 		return idx.Name
 	}
@@ -550,6 +1092,19 @@ func getIndexName(idx *model.Index) model.MaybeIdent {
 	return model.MaybeIdent{}
 }
 
+// hasIndexName returns whether the table has an index with the name.
+func hasIndexName(table *model.Table, name model.MaybeIdent) bool {
+	if !name.Valid {
+		return false
+	}
+	for _, idx := range table.Indexes {
+		if n := getIndexName(idx); n.Valid && strings.EqualFold(string(n.Ident), string(name.Ident)) {
+			return true
+		}
+	}
+	return false
+}
+
 // equalIndex returns whether index a and b have same definition, excluding their names.
 func equalIndex(a, b *model.Index) bool {
 	if a.Table != b.Table {
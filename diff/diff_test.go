@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/schemalex-deploy"
 	"github.com/shogo82148/schemalex-deploy/diff"
 	"github.com/shogo82148/schemalex-deploy/internal/database"
 	"github.com/shogo82148/schemalex-deploy/internal/util"
@@ -154,6 +155,51 @@ var specs = []Spec{
 			"ALTER TABLE `fuga` ADD PRIMARY KEY (`id`)",
 		},
 	},
+	{
+		Name: "add primary key by inline key",
+		Before: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL )",
+		},
+		After: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL KEY )",
+		},
+		Expect: []string{
+			"ALTER TABLE `fuga` ADD PRIMARY KEY (`id`)",
+		},
+	},
+	{
+		Name: "inline key is same as primary key",
+		Before: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL KEY )",
+		},
+		After: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) )",
+		},
+	},
+	{
+		Name: "drop auto_increment before dropping primary key",
+		Before: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL AUTO_INCREMENT, `a` INTEGER NOT NULL, PRIMARY KEY (`id`) )",
+		},
+		After: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, PRIMARY KEY (`a`, `id`) )",
+		},
+		Expect: []string{
+			"ALTER TABLE `fuga` CHANGE COLUMN `id` `id` INT (11) NOT NULL, DROP PRIMARY KEY, ADD PRIMARY KEY (`a`, `id`)",
+		},
+	},
+	{
+		Name: "move auto_increment to another column",
+		Before: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL AUTO_INCREMENT, `a` INTEGER NOT NULL, PRIMARY KEY (`id`) )",
+		},
+		After: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL AUTO_INCREMENT, PRIMARY KEY (`a`) )",
+		},
+		Expect: []string{
+			"ALTER TABLE `fuga` CHANGE COLUMN `id` `id` INT (11) NOT NULL, DROP PRIMARY KEY, ADD PRIMARY KEY (`a`), CHANGE COLUMN `a` `a` INT (11) NOT NULL AUTO_INCREMENT",
+		},
+	},
 	{
 		Name: "drop unique key",
 		Before: []string{
@@ -226,8 +272,7 @@ var specs = []Spec{
 		Expect: []string{
 			"ALTER TABLE `fuga` " +
 				"DROP FOREIGN KEY `fsym`, " +
-				"DROP INDEX `fsym`, " +
-				"ADD INDEX `ksym` (`fid`), " +
+				"RENAME INDEX `fsym` TO `ksym`, " +
 				"ADD CONSTRAINT `ksym` FOREIGN KEY (`fid`) REFERENCES `f` (`id`)",
 		},
 	},
@@ -244,8 +289,134 @@ var specs = []Spec{
 		Expect: []string{
 			"ALTER TABLE `fuga` " +
 				"DROP FOREIGN KEY `fk`, " +
-				"DROP INDEX `fk`, " +
-				"ADD INDEX `fid` (`fid`)",
+				"RENAME INDEX `fk` TO `fid`",
+		},
+	},
+	{
+		Name:   "create referred tables first",
+		Before: []string{},
+		After: []string{
+			"CREATE TABLE `a` ( `id` INTEGER NOT NULL, `bid` INTEGER NOT NULL, PRIMARY KEY (`id`), CONSTRAINT `a_fk` FOREIGN KEY (`bid`) REFERENCES `b` (`id`) )",
+			"CREATE TABLE `b` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) )",
+		},
+		Expect: []string{
+			"CREATE TABLE `b` (\n`id` INT (11) NOT NULL,\nPRIMARY KEY (`id`)\n)",
+			"CREATE TABLE `a` (\n`id` INT (11) NOT NULL,\n`bid` INT (11) NOT NULL,\nPRIMARY KEY (`id`),\n" +
+				"INDEX `a_fk` (`bid`),\nCONSTRAINT `a_fk` FOREIGN KEY (`bid`) REFERENCES `b` (`id`)\n)",
+		},
+	},
+	{
+		Name:   "create tables with circular references",
+		Before: []string{},
+		After: []string{
+			"CREATE TABLE `a` ( `id` INTEGER NOT NULL, `bid` INTEGER NOT NULL, PRIMARY KEY (`id`), CONSTRAINT `a_fk` FOREIGN KEY (`bid`) REFERENCES `b` (`id`) )",
+			"CREATE TABLE `b` ( `id` INTEGER NOT NULL, `aid` INTEGER NOT NULL, PRIMARY KEY (`id`), CONSTRAINT `b_fk` FOREIGN KEY (`aid`) REFERENCES `a` (`id`) )",
+		},
+		Expect: []string{
+			"CREATE TABLE `a` (\n`id` INT (11) NOT NULL,\n`bid` INT (11) NOT NULL,\nPRIMARY KEY (`id`),\nINDEX `a_fk` (`bid`)\n)",
+			"CREATE TABLE `b` (\n`id` INT (11) NOT NULL,\n`aid` INT (11) NOT NULL,\nPRIMARY KEY (`id`),\n" +
+				"INDEX `b_fk` (`aid`),\nCONSTRAINT `b_fk` FOREIGN KEY (`aid`) REFERENCES `a` (`id`)\n)",
+			"ALTER TABLE `a` ADD CONSTRAINT `a_fk` FOREIGN KEY (`bid`) REFERENCES `b` (`id`)",
+		},
+	},
+	{
+		Name: "drop referring tables first",
+		Before: []string{
+			"CREATE TABLE `a` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) )",
+			"CREATE TABLE `b` ( `id` INTEGER NOT NULL, `aid` INTEGER NOT NULL, PRIMARY KEY (`id`), CONSTRAINT `b_fk` FOREIGN KEY (`aid`) REFERENCES `a` (`id`) )",
+		},
+		After: []string{},
+		Expect: []string{
+			"DROP TABLE `b`",
+			"DROP TABLE `a`",
+		},
+	},
+	{
+		Name: "drop tables with circular references",
+		Before: []string{
+			"CREATE TABLE `a` ( `id` INTEGER NOT NULL, `bid` INTEGER NOT NULL, PRIMARY KEY (`id`), CONSTRAINT `a_fk` FOREIGN KEY (`bid`) REFERENCES `b` (`id`) )",
+			"CREATE TABLE `b` ( `id` INTEGER NOT NULL, `aid` INTEGER NOT NULL, PRIMARY KEY (`id`), CONSTRAINT `b_fk` FOREIGN KEY (`aid`) REFERENCES `a` (`id`) )",
+		},
+		After: []string{},
+		Expect: []string{
+			"ALTER TABLE `a` DROP FOREIGN KEY `a_fk`",
+			"DROP TABLE `b`",
+			"DROP TABLE `a`",
+		},
+	},
+	{
+		Name: "drop foreign keys before the referred table",
+		Before: []string{
+			"CREATE TABLE `a` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) )",
+			"CREATE TABLE `b` ( `id` INTEGER NOT NULL, `aid` INTEGER NOT NULL, PRIMARY KEY (`id`), CONSTRAINT `b_fk` FOREIGN KEY (`aid`) REFERENCES `a` (`id`) )",
+		},
+		After: []string{
+			"CREATE TABLE `b` ( `id` INTEGER NOT NULL, `aid` INTEGER NOT NULL, PRIMARY KEY (`id`) )",
+		},
+		Expect: []string{
+			"ALTER TABLE `b` DROP FOREIGN KEY `b_fk`",
+			"DROP TABLE `a`",
+			"ALTER TABLE `b` DROP INDEX `b_fk`",
+		},
+	},
+	{
+		Name: "change the columns of foreign keys",
+		Before: []string{
+			"CREATE TABLE `a` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) )",
+			"CREATE TABLE `b` ( `id` INTEGER NOT NULL, `aid` INTEGER NOT NULL, PRIMARY KEY (`id`), CONSTRAINT `b_fk` FOREIGN KEY (`aid`) REFERENCES `a` (`id`) )",
+		},
+		After: []string{
+			"CREATE TABLE `a` ( `id` BIGINT NOT NULL, PRIMARY KEY (`id`) )",
+			"CREATE TABLE `b` ( `id` INTEGER NOT NULL, `aid` BIGINT NOT NULL, PRIMARY KEY (`id`), CONSTRAINT `b_fk` FOREIGN KEY (`aid`) REFERENCES `a` (`id`) )",
+		},
+		Expect: []string{
+			"ALTER TABLE `b` DROP FOREIGN KEY `b_fk`",
+			"ALTER TABLE `a` CHANGE COLUMN `id` `id` BIGINT (20) NOT NULL",
+			"ALTER TABLE `b` CHANGE COLUMN `aid` `aid` BIGINT (20) NOT NULL, ADD CONSTRAINT `b_fk` FOREIGN KEY (`aid`) REFERENCES `a` (`id`)",
+		},
+	},
+	{
+		Name: "add foreign keys after the referred columns are changed",
+		Before: []string{
+			"CREATE TABLE `a` ( `id` INTEGER NOT NULL, `bid` INTEGER NOT NULL, PRIMARY KEY (`id`), CONSTRAINT `a_fk` FOREIGN KEY (`bid`) REFERENCES `b` (`id`) )",
+			"CREATE TABLE `b` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) )",
+		},
+		After: []string{
+			"CREATE TABLE `a` ( `id` INTEGER NOT NULL, `bid` BIGINT NOT NULL, PRIMARY KEY (`id`), CONSTRAINT `a_fk` FOREIGN KEY (`bid`) REFERENCES `b` (`id`) )",
+			"CREATE TABLE `b` ( `id` BIGINT NOT NULL, PRIMARY KEY (`id`) )",
+		},
+		Expect: []string{
+			"ALTER TABLE `a` DROP FOREIGN KEY `a_fk`",
+			"ALTER TABLE `a` CHANGE COLUMN `bid` `bid` BIGINT (20) NOT NULL",
+			"ALTER TABLE `b` CHANGE COLUMN `id` `id` BIGINT (20) NOT NULL",
+			"ALTER TABLE `a` ADD CONSTRAINT `a_fk` FOREIGN KEY (`bid`) REFERENCES `b` (`id`)",
+		},
+	},
+	{
+		Name: "change the columns of self-referencing foreign keys",
+		Before: []string{
+			"CREATE TABLE `a` ( `id` INTEGER NOT NULL, `pid` INTEGER NOT NULL, PRIMARY KEY (`id`), CONSTRAINT `a_fk` FOREIGN KEY (`pid`) REFERENCES `a` (`id`) )",
+		},
+		After: []string{
+			"CREATE TABLE `a` ( `id` BIGINT NOT NULL, `pid` BIGINT NOT NULL, PRIMARY KEY (`id`), CONSTRAINT `a_fk` FOREIGN KEY (`pid`) REFERENCES `a` (`id`) )",
+		},
+		Expect: []string{
+			"ALTER TABLE `a` DROP FOREIGN KEY `a_fk`",
+			"ALTER TABLE `a` CHANGE COLUMN `id` `id` BIGINT (20) NOT NULL, CHANGE COLUMN `pid` `pid` BIGINT (20) NOT NULL, ADD CONSTRAINT `a_fk` FOREIGN KEY (`pid`) REFERENCES `a` (`id`)",
+		},
+	},
+	{
+		Name: "keep foreign keys on columns whose types don't change",
+		Before: []string{
+			"CREATE TABLE `a` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) )",
+			"CREATE TABLE `b` ( `id` INTEGER NOT NULL, `aid` INTEGER NOT NULL, PRIMARY KEY (`id`), CONSTRAINT `b_fk` FOREIGN KEY (`aid`) REFERENCES `a` (`id`) )",
+		},
+		After: []string{
+			"CREATE TABLE `a` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) )",
+			"CREATE TABLE `b` ( `id` INTEGER NOT NULL, `aid` INTEGER NOT NULL COMMENT 'a', PRIMARY KEY (`id`), CONSTRAINT `b_fk` FOREIGN KEY (`aid`) REFERENCES `a` (`id`) )",
+		},
+		Expect: []string{
+			"ALTER TABLE `b` CHANGE COLUMN `aid` `aid` INT (11) NOT NULL COMMENT 'a'",
 		},
 	},
 	{
@@ -348,6 +519,257 @@ var specs = []Spec{
 		},
 	},
 
+	{
+		Name: "drop anonymous unique key without the current schema",
+		Before: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR(20), INDEX `name` (`id`), UNIQUE KEY (`name`) )",
+		},
+		After: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR(20), INDEX `name` (`id`) )",
+		},
+		Expect: []string{
+			"ALTER TABLE `fuga` DROP INDEX `name_2`",
+		},
+	},
+	{
+		Name: "drop anonymous FOREIGN KEY without the current schema",
+		Before: []string{
+			"CREATE TABLE `f` ( `id` INTEGER NOT NULL AUTO_INCREMENT, PRIMARY KEY (`id`) )",
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `fid` INTEGER NOT NULL, `gid` INTEGER NOT NULL, " +
+				"FOREIGN KEY (fid) REFERENCES f (id), FOREIGN KEY (gid) REFERENCES f (id) )",
+		},
+		After: []string{
+			"CREATE TABLE `f` ( `id` INTEGER NOT NULL AUTO_INCREMENT, PRIMARY KEY (`id`) )",
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `fid` INTEGER NOT NULL, `gid` INTEGER NOT NULL, " +
+				"FOREIGN KEY (fid) REFERENCES f (id) )",
+		},
+		Expect: []string{
+			"ALTER TABLE `fuga` DROP FOREIGN KEY `fuga_ibfk_2`, DROP INDEX `gid`",
+		},
+	},
+	{
+		Name: "not change anonymous index named explicitly",
+		Before: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, INDEX (`id`) )",
+		},
+		After: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, INDEX `id` (`id`) )",
+		},
+		Expect: []string{},
+	},
+	{
+		Name: "change table options",
+		Before: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ENGINE=InnoDB COMMENT='old' AUTO_INCREMENT=10",
+		},
+		After: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ENGINE=InnoDB ROW_FORMAT=COMPRESSED COMMENT='new' AUTO_INCREMENT=20",
+		},
+		Expect: []string{
+			"ALTER TABLE `fuga` ROW_FORMAT = COMPRESSED, COMMENT = 'new'",
+		},
+	},
+	{
+		Name: "reset table options",
+		Before: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ENGINE=MyISAM ROW_FORMAT=COMPACT COMMENT='old'",
+		},
+		After: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL )",
+		},
+		Expect: []string{
+			"ALTER TABLE `fuga` ENGINE = InnoDB, ROW_FORMAT = DEFAULT, COMMENT = ''",
+		},
+	},
+	{
+		Name: "change the default character set",
+		Before: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) DEFAULT CHARSET=utf8mb4",
+			"CREATE TABLE `hoge` ( `id` INTEGER NOT NULL ) DEFAULT CHARSET=utf8mb4",
+			"CREATE TABLE `piyo` ( `id` INTEGER NOT NULL ) COLLATE=utf8mb4_bin",
+		},
+		After: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) DEFAULT CHARSET=latin1",
+			"CREATE TABLE `hoge` ( `id` INTEGER NOT NULL ) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin",
+			"CREATE TABLE `piyo` ( `id` INTEGER NOT NULL )",
+		},
+		Expect: []string{
+			"ALTER TABLE `fuga` DEFAULT CHARACTER SET = latin1",
+			"ALTER TABLE `hoge` DEFAULT COLLATE = utf8mb4_bin",
+			"ALTER TABLE `piyo` DEFAULT CHARACTER SET = utf8mb4",
+		},
+	},
+	{
+		Name: "convert the character set of the table",
+		Before: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) NOT NULL, `code` VARCHAR (10) CHARACTER SET latin1 NOT NULL, `body` TEXT ) DEFAULT CHARSET=utf8mb3",
+			"CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) CHARACTER SET utf8mb3 NOT NULL ) DEFAULT CHARSET=utf8mb3",
+		},
+		After: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) NOT NULL, `code` VARCHAR (10) CHARACTER SET latin1 NOT NULL, `body` TEXT ) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin",
+			"CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) CHARACTER SET utf8mb4 NOT NULL ) DEFAULT CHARSET=utf8mb4",
+		},
+		Expect: []string{
+			"ALTER TABLE `fuga` CONVERT TO CHARACTER SET utf8mb4 COLLATE utf8mb4_bin, MODIFY COLUMN `body` TEXT, MODIFY COLUMN `code` VARCHAR (10) CHARACTER SET `latin1` NOT NULL",
+			"ALTER TABLE `hoge` CONVERT TO CHARACTER SET utf8mb4",
+		},
+	},
+	{
+		Name: "convert the collation of the table",
+		Before: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) NOT NULL ) DEFAULT CHARSET=utf8mb4",
+		},
+		After: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) NOT NULL ) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin",
+		},
+		Expect: []string{
+			"ALTER TABLE `fuga` CONVERT TO CHARACTER SET utf8mb4 COLLATE utf8mb4_bin",
+		},
+	},
+	{
+		Name: "rename table",
+		Before: []string{
+			"CREATE TABLE `f` ( `id` INTEGER NOT NULL AUTO_INCREMENT, PRIMARY KEY (`id`) )",
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `fid` INTEGER NOT NULL, CONSTRAINT `fk` FOREIGN KEY (`fid`) REFERENCES `f` (`id`) )",
+		},
+		After: []string{
+			"-- schemalex:renamed-from f\n" +
+				"CREATE TABLE `foo` ( `id` INTEGER NOT NULL AUTO_INCREMENT, PRIMARY KEY (`id`) )",
+			"/* schemalex:renamed-from `fuga` */\n" +
+				"CREATE TABLE `bar` ( `id` INTEGER NOT NULL, `fid` INTEGER NOT NULL, `name` TEXT, CONSTRAINT `fk` FOREIGN KEY (`fid`) REFERENCES `foo` (`id`) )",
+		},
+		Expect: []string{
+			"RENAME TABLE `f` TO `foo`",
+			"RENAME TABLE `fuga` TO `bar`",
+			"ALTER TABLE `bar` ADD COLUMN `name` TEXT AFTER `fid`",
+		},
+	},
+	{
+		Name: "not rename the renamed table",
+		Before: []string{
+			"CREATE TABLE `foo` ( `id` INTEGER NOT NULL )",
+		},
+		After: []string{
+			"-- schemalex:renamed-from f\n" +
+				"CREATE TABLE `foo` ( `id` INTEGER NOT NULL )",
+		},
+		Expect: []string{},
+	},
+	{
+		Name: "rename column",
+		Before: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR(20) NOT NULL, INDEX `idx_name` (`name`) )",
+		},
+		After: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, /* schemalex:renamed-from name */ `title` VARCHAR(20) NOT NULL, INDEX `idx_name` (`title`) )",
+		},
+		Expect: []string{
+			"ALTER TABLE `fuga` RENAME COLUMN `name` TO `title`",
+		},
+	},
+	{
+		Name: "rename column with trailing comment",
+		Before: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `old_id` INTEGER NOT NULL, `name` VARCHAR(20) NOT NULL )",
+		},
+		After: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL,\n`new_id` INTEGER NOT NULL, -- schemalex:renamed-from old_id\n`name` VARCHAR(20) NOT NULL )",
+		},
+		Expect: []string{
+			"ALTER TABLE `fuga` RENAME COLUMN `old_id` TO `new_id`",
+		},
+	},
+	{
+		Name: "rename and change column",
+		Before: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR(20) NOT NULL )",
+		},
+		After: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `title` VARCHAR(40) NOT NULL /* schemalex:renamed-from `name` */ )",
+		},
+		Expect: []string{
+			"ALTER TABLE `fuga` CHANGE COLUMN `name` `title` VARCHAR (40) NOT NULL",
+		},
+	},
+	{
+		Name: "rename referred column",
+		Before: []string{
+			"CREATE TABLE `f` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) )",
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `fid` INTEGER NOT NULL, CONSTRAINT `fk` FOREIGN KEY (`fid`) REFERENCES `f` (`id`) )",
+		},
+		After: []string{
+			"CREATE TABLE `f` ( `f_id` INTEGER NOT NULL /* schemalex:renamed-from id */, PRIMARY KEY (`f_id`) )",
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `fid` INTEGER NOT NULL, CONSTRAINT `fk` FOREIGN KEY (`fid`) REFERENCES `f` (`f_id`) )",
+		},
+		Expect: []string{
+			"ALTER TABLE `f` RENAME COLUMN `id` TO `f_id`",
+		},
+	},
+	{
+		Name: "rename index",
+		Before: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER, `b` INTEGER, INDEX `idx_a` (`a`), UNIQUE INDEX (`b`), INDEX `idx_c` (`a`, `b`) )",
+		},
+		After: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER, `b` INTEGER, INDEX `a` (`a`), UNIQUE INDEX `uniq_b` (`b`), INDEX `idx_d` (`b`, `a`) )",
+		},
+		Expect: []string{
+			"ALTER TABLE `fuga` DROP INDEX `idx_c`, RENAME INDEX `b` TO `uniq_b`, RENAME INDEX `idx_a` TO `a`, ADD INDEX `idx_d` (`b`, `a`)",
+		},
+	},
+	{
+		Name: "not rename index with different options",
+		Tests: []string{
+			"CREATE TABLE `hoge` ( `txt` TEXT, FULLTEXT INDEX `ft_idx` (`txt`) WITH PARSER `ngram`)",
+		},
+		Before: []string{
+			"CREATE TABLE `hoge` ( `txt` TEXT, FULLTEXT INDEX `ft_a` (`txt`) )",
+		},
+		After: []string{
+			"CREATE TABLE `hoge` ( `txt` TEXT, FULLTEXT INDEX `ft_b` (`txt`) WITH PARSER `ngram`)",
+		},
+		Expect: []string{
+			"ALTER TABLE `hoge` DROP INDEX `ft_a`, ADD FULLTEXT INDEX `ft_b` (`txt`) WITH PARSER `ngram`",
+		},
+	},
+	{
+		Name: "move columns",
+		Before: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER, `b` INTEGER, `c` INTEGER, `d` INTEGER )",
+		},
+		After: []string{
+			"CREATE TABLE `fuga` ( `d` INTEGER, `id` INTEGER NOT NULL, `a` INTEGER, `c` INTEGER, `b` INTEGER )",
+		},
+		Expect: []string{
+			"ALTER TABLE `fuga` MODIFY COLUMN `d` INT (11) DEFAULT NULL FIRST, MODIFY COLUMN `b` INT (11) DEFAULT NULL AFTER `c`",
+		},
+	},
+	{
+		Name: "move and change columns",
+		Before: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER, `b` INTEGER )",
+		},
+		After: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `b` BIGINT, `new` INTEGER, `c` INTEGER /* schemalex:renamed-from a */ )",
+		},
+		Expect: []string{
+			"ALTER TABLE `fuga` ADD COLUMN `new` INT (11) DEFAULT NULL AFTER `b`, " +
+				"CHANGE COLUMN `a` `c` INT (11) DEFAULT NULL AFTER `new`, " +
+				"CHANGE COLUMN `b` `b` BIGINT (20) DEFAULT NULL",
+		},
+	},
+	{
+		Name: "move renamed columns",
+		Before: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER, `b` INTEGER )",
+		},
+		After: []string{
+			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `b` INTEGER, `c` INTEGER /* schemalex:renamed-from a */ )",
+		},
+		Expect: []string{
+			"ALTER TABLE `fuga` CHANGE COLUMN `a` `c` INT (11) DEFAULT NULL AFTER `b`",
+		},
+	},
 	{
 		Name: "not change to query what generated by show create table",
 		// human input
@@ -465,6 +887,790 @@ func TestDiff(t *testing.T) {
 	}
 }
 
+func TestDiff_RenamedFromUnknownColumn(t *testing.T) {
+	const before = "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR(20) NOT NULL )"
+	const after = "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, /* schemalex:renamed-from old_name */ `title` VARCHAR(20) NOT NULL )"
+	if err := diff.Strings(&bytes.Buffer{}, before, after); err == nil {
+		t.Error("want error for the annotation without the old column, got nil")
+	}
+}
+
+func TestDiff_IgnoredTableOptions(t *testing.T) {
+	before := "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) COMMENT='old' AUTO_INCREMENT=10;"
+	after := "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) COMMENT='new' AUTO_INCREMENT=20;"
+	tests := []struct {
+		name    string
+		options []diff.Option
+		want    string
+	}{
+		{
+			name: "default",
+			want: "ALTER TABLE `fuga` COMMENT = 'new';\n",
+		},
+		{
+			name:    "ignore nothing",
+			options: []diff.Option{diff.WithIgnoredTableOptions()},
+			want:    "ALTER TABLE `fuga` COMMENT = 'new', AUTO_INCREMENT = 20;\n",
+		},
+		{
+			name:    "ignore comment",
+			options: []diff.Option{diff.WithIgnoredTableOptions("auto_increment", "comment")},
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := diff.Strings(&buf, before, after, tt.options...); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, buf.String()); diff != "" {
+				t.Errorf("mismatch (-want/+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDiff_ColumnReordering(t *testing.T) {
+	before := "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER, `b` INTEGER );\n" +
+		"CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` INTEGER, `b` INTEGER );"
+	after := "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `b` INTEGER, `a` INTEGER );\n" +
+		"CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `b` INTEGER, `a` INTEGER );"
+	tests := []struct {
+		name    string
+		options []diff.Option
+		want    string
+	}{
+		{
+			name: "default",
+			want: "ALTER TABLE `fuga` MODIFY COLUMN `a` INT (11) DEFAULT NULL AFTER `b`;\n" +
+				"ALTER TABLE `hoge` MODIFY COLUMN `a` INT (11) DEFAULT NULL AFTER `b`;\n",
+		},
+		{
+			name:    "disabled",
+			options: []diff.Option{diff.WithColumnReordering(false)},
+			want:    "",
+		},
+		{
+			name:    "disabled on a table",
+			options: []diff.Option{diff.WithColumnReordering(false, "FUGA")},
+			want:    "ALTER TABLE `hoge` MODIFY COLUMN `a` INT (11) DEFAULT NULL AFTER `b`;\n",
+		},
+		{
+			name:    "enabled on a table",
+			options: []diff.Option{diff.WithColumnReordering(false), diff.WithColumnReordering(true, "fuga")},
+			want:    "ALTER TABLE `fuga` MODIFY COLUMN `a` INT (11) DEFAULT NULL AFTER `b`;\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := diff.Strings(&buf, before, after, tt.options...); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, buf.String()); diff != "" {
+				t.Errorf("mismatch (-want/+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDiff_Operations(t *testing.T) {
+	p := schemalex.New()
+	from, err := p.ParseString("CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` INTEGER, `b` INTEGER, INDEX `ia` (`a`) );\n" +
+		"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );")
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := p.ParseString("CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` BIGINT, `c` INTEGER, INDEX `ic` (`c`) );\n" +
+		"CREATE TABLE `piyo` ( `id` INTEGER NOT NULL );")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmts, err := diff.Diff(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 3 {
+		t.Fatalf("want 3 statements, got %d: %v", len(stmts), stmts)
+	}
+
+	drop, ok := stmts[0].(*diff.DropTable)
+	if !ok || drop.Table.Name != "fuga" {
+		t.Errorf("want DROP TABLE `fuga`, got %#v", stmts[0])
+	}
+	create, ok := stmts[1].(*diff.CreateTable)
+	if !ok || create.Table.Name != "piyo" {
+		t.Errorf("want CREATE TABLE `piyo`, got %#v", stmts[1])
+	}
+	alter, ok := stmts[2].(*diff.AlterTable)
+	if !ok {
+		t.Fatalf("want ALTER TABLE, got %#v", stmts[2])
+	}
+
+	var kinds []string
+	for _, c := range alter.Clauses {
+		switch c := c.(type) {
+		case *diff.DropIndex:
+			kinds = append(kinds, "drop index "+string(c.Name))
+		case *diff.DropColumn:
+			kinds = append(kinds, "drop column "+string(c.Column.Name))
+		case *diff.AddColumn:
+			kinds = append(kinds, "add column "+string(c.Column.Name)+" after "+string(c.Position.After.Name))
+		case *diff.ChangeColumn:
+			kinds = append(kinds, "change column "+string(c.From.Name)+" from "+c.From.Type.String()+" to "+c.To.Type.String())
+		case *diff.AddIndex:
+			kinds = append(kinds, "add index "+string(c.Index.Name.Ident))
+		default:
+			kinds = append(kinds, fmt.Sprintf("%T", c))
+		}
+	}
+	want := []string{
+		"drop index ia",
+		"drop column b",
+		"add column c after a",
+		"change column a from INT to BIGINT",
+		"add index ic",
+	}
+	if diff := cmp.Diff(want, kinds); diff != "" {
+		t.Errorf("clauses mismatch (-want,+got):\n%s", diff)
+	}
+}
+
+func TestDiff_Ignore(t *testing.T) {
+	tests := []struct {
+		name   string
+		ignore *diff.Ignore
+		before string
+		after  string
+		want   string
+	}{
+		{
+			name:   "ignore tables by glob",
+			ignore: &diff.Ignore{Tables: []string{"_*_gho", "_*_DEL", "schemalex_revision"}},
+			before: "CREATE TABLE `_hoge_gho` ( `id` INTEGER NOT NULL );\n" +
+				"CREATE TABLE `_hoge_del` ( `id` INTEGER NOT NULL );\n" +
+				"CREATE TABLE `schemalex_revision` ( `id` INTEGER NOT NULL );",
+			after: "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );\n" +
+				"CREATE TABLE `_hoge_gho` ( `id` BIGINT NOT NULL );",
+			want: "CREATE TABLE `hoge` (\n`id` INT (11) NOT NULL\n);\n",
+		},
+		{
+			name:   "ignore tables by regular expression",
+			ignore: &diff.Ignore{Tables: []string{"/^tmp_[0-9]+$/"}},
+			before: "CREATE TABLE `tmp_1` ( `id` INTEGER NOT NULL );\n" +
+				"CREATE TABLE `tmp_a` ( `id` INTEGER NOT NULL );",
+			after: "",
+			want:  "DROP TABLE `tmp_a`;\n",
+		},
+		{
+			name:   "ignore columns and their indexes",
+			ignore: &diff.Ignore{Columns: []string{"hoge.tmp", "updated_*"}},
+			before: "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `tmp` INTEGER NOT NULL, `updated_at` DATETIME NOT NULL, INDEX `itmp` (`tmp`) );\n" +
+				"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `tmp` INTEGER NOT NULL, `updated_at` DATETIME NOT NULL );",
+			after: "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );\n" +
+				"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			want: "ALTER TABLE `fuga` DROP COLUMN `tmp`;\n",
+		},
+		{
+			name:   "ignore table options",
+			ignore: &diff.Ignore{TableOptions: []string{"ENGINE"}},
+			before: "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL ) ENGINE = MyISAM;",
+			after:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL ) ENGINE = InnoDB;",
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := diff.Strings(&buf, tt.before, tt.after, diff.WithTransaction(false), diff.WithIgnore(tt.ignore)); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, buf.String()); diff != "" {
+				t.Errorf("unexpected result (-want,+got):\n%s", diff)
+			}
+		})
+	}
+
+	err := diff.Strings(&bytes.Buffer{}, "", "", diff.WithIgnore(&diff.Ignore{Tables: []string{"/[/"}}))
+	if err == nil {
+		t.Error("want error for the invalid pattern, got nil")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		name        string
+		a           string
+		b           string
+		want        bool
+		explanation string
+	}{
+		{
+			name: "same schema in different forms",
+			a:    "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL AUTO_INCREMENT, `name` VARCHAR (20), PRIMARY KEY (`id`), KEY `idx_name` (`name`) ) AUTO_INCREMENT = 10;",
+			b:    "create table hoge ( id int(11) not null auto_increment primary key, name varchar(20) default null, index idx_name (name) );",
+			want: true,
+		},
+		{
+			name:        "different schemas",
+			a:           "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );",
+			b:           "CREATE TABLE `hoge` ( `id` BIGINT NOT NULL );",
+			want:        false,
+			explanation: "ALTER TABLE `hoge` CHANGE COLUMN `id` `id` BIGINT (20) NOT NULL;\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := schemalex.New()
+			a, err := p.ParseString(tt.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := p.ParseString(tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, explanation, err := diff.Equal(a, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("want %t, got %t: %s", tt.want, got, explanation)
+			}
+			if diff := cmp.Diff(tt.explanation, explanation); diff != "" {
+				t.Errorf("explanation mismatch (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEqual_Error(t *testing.T) {
+	p := schemalex.New()
+	a, err := p.ParseString("CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR(20) NOT NULL )")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := p.ParseString("CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, /* schemalex:renamed-from old_name */ `title` VARCHAR(20) NOT NULL )")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, explanation, err := diff.Equal(a, b)
+	if err == nil {
+		t.Errorf("want error, got %t: %q", got, explanation)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   []string
+	}{
+		{
+			name:   "create table",
+			before: "",
+			after:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );",
+			want:   []string{"safe"},
+		},
+		{
+			name:   "drop table",
+			before: "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );",
+			after:  "",
+			want:   []string{"destructive: drops table `hoge`"},
+		},
+		{
+			name:   "add and drop columns",
+			before: "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` INTEGER );",
+			after:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `b` INTEGER );",
+			want: []string{
+				"destructive: drops column `a`",
+				"safe",
+			},
+		},
+		{
+			name:   "widen integer",
+			before: "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );",
+			after:  "CREATE TABLE `hoge` ( `id` BIGINT NOT NULL );",
+			want:   []string{"blocking: rebuilds the table to change column `id`"},
+		},
+		{
+			name:   "narrow integer",
+			before: "CREATE TABLE `hoge` ( `id` BIGINT NOT NULL );",
+			after:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );",
+			want:   []string{"destructive: narrows the type of column `id`, may truncate data"},
+		},
+		{
+			name:   "make integer unsigned",
+			before: "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );",
+			after:  "CREATE TABLE `hoge` ( `id` INTEGER UNSIGNED NOT NULL );",
+			want:   []string{"destructive: changes the sign of column `id`, may truncate data"},
+		},
+		{
+			name:   "shorten varchar",
+			before: "CREATE TABLE `hoge` ( `name` VARCHAR (255) NOT NULL );",
+			after:  "CREATE TABLE `hoge` ( `name` VARCHAR (64) NOT NULL );",
+			want:   []string{"destructive: narrows the type of column `name`, may truncate data"},
+		},
+		{
+			name:   "varchar to text",
+			before: "CREATE TABLE `hoge` ( `name` VARCHAR (255) NOT NULL );",
+			after:  "CREATE TABLE `hoge` ( `name` TEXT NOT NULL );",
+			want:   []string{"blocking: rebuilds the table to change column `name`"},
+		},
+		{
+			name:   "remove enum value",
+			before: "CREATE TABLE `hoge` ( `kind` ENUM ('a', 'b') NOT NULL );",
+			after:  "CREATE TABLE `hoge` ( `kind` ENUM ('a') NOT NULL );",
+			want:   []string{"destructive: narrows the type of column `kind`, may truncate data"},
+		},
+		{
+			name:   "convert character set",
+			before: "CREATE TABLE `hoge` ( `name` VARCHAR (255) CHARACTER SET latin1 NOT NULL );",
+			after:  "CREATE TABLE `hoge` ( `name` VARCHAR (255) CHARACTER SET utf8mb4 NOT NULL );",
+			want:   []string{"destructive: converts column `name` to character set utf8mb4"},
+		},
+		{
+			name:   "convert table",
+			before: "CREATE TABLE `hoge` ( `name` VARCHAR (255) NOT NULL ) DEFAULT CHARSET=utf8mb3;",
+			after:  "CREATE TABLE `hoge` ( `name` VARCHAR (255) NOT NULL ) DEFAULT CHARSET=utf8mb4;",
+			want:   []string{"destructive: converts table `hoge` to character set utf8mb4"},
+		},
+		{
+			name:   "add NOT NULL",
+			before: "CREATE TABLE `hoge` ( `name` VARCHAR (255) );",
+			after:  "CREATE TABLE `hoge` ( `name` VARCHAR (255) NOT NULL );",
+			want:   []string{"destructive: adds NOT NULL to column `name`"},
+		},
+		{
+			name:   "change comment",
+			before: "CREATE TABLE `hoge` ( `name` VARCHAR (255) NOT NULL COMMENT 'a' );",
+			after:  "CREATE TABLE `hoge` ( `name` VARCHAR (255) NOT NULL COMMENT 'b' );",
+			want:   []string{"safe"},
+		},
+		{
+			name:   "add primary key",
+			before: "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );",
+			after:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) );",
+			want:   []string{"blocking: rebuilds the table to add the primary key"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := schemalex.New()
+			from, err := p.ParseString(tt.before)
+			if err != nil {
+				t.Fatal(err)
+			}
+			to, err := p.ParseString(tt.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stmts, err := diff.Diff(from, to)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, c := range diff.Classify(stmts) {
+				got = append(got, c.String())
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("changes mismatch (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDataLoss(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   diff.DataLoss
+	}{
+		{
+			name:   "extend varchar",
+			before: "CREATE TABLE `hoge` ( `a` VARCHAR (50) NOT NULL );",
+			after:  "CREATE TABLE `hoge` ( `a` VARCHAR (100) NOT NULL );",
+			want:   diff.DataWidening,
+		},
+		{
+			name:   "shorten varchar",
+			before: "CREATE TABLE `hoge` ( `a` VARCHAR (100) NOT NULL );",
+			after:  "CREATE TABLE `hoge` ( `a` VARCHAR (50) NOT NULL );",
+			want:   diff.DataNarrowing,
+		},
+		{
+			name:   "varchar to text",
+			before: "CREATE TABLE `hoge` ( `a` VARCHAR (255) NOT NULL );",
+			after:  "CREATE TABLE `hoge` ( `a` TEXT NOT NULL );",
+			want:   diff.DataWidening,
+		},
+		{
+			name:   "utf8mb4 varchar to tinytext",
+			before: "CREATE TABLE `hoge` ( `a` VARCHAR (100) NOT NULL ) DEFAULT CHARSET=utf8mb4;",
+			after:  "CREATE TABLE `hoge` ( `a` TINYTEXT NOT NULL ) DEFAULT CHARSET=utf8mb4;",
+			want:   diff.DataNarrowing,
+		},
+		{
+			name:   "latin1 varchar to tinytext",
+			before: "CREATE TABLE `hoge` ( `a` VARCHAR (100) NOT NULL ) DEFAULT CHARSET=latin1;",
+			after:  "CREATE TABLE `hoge` ( `a` TINYTEXT NOT NULL ) DEFAULT CHARSET=latin1;",
+			want:   diff.DataWidening,
+		},
+		{
+			name:   "varchar to varbinary",
+			before: "CREATE TABLE `hoge` ( `a` VARCHAR (255) NOT NULL );",
+			after:  "CREATE TABLE `hoge` ( `a` VARBINARY (255) NOT NULL );",
+			want:   diff.DataNarrowing,
+		},
+		{
+			name:   "int to bigint",
+			before: "CREATE TABLE `hoge` ( `a` INTEGER NOT NULL );",
+			after:  "CREATE TABLE `hoge` ( `a` BIGINT NOT NULL );",
+			want:   diff.DataWidening,
+		},
+		{
+			name:   "bigint to int",
+			before: "CREATE TABLE `hoge` ( `a` BIGINT NOT NULL );",
+			after:  "CREATE TABLE `hoge` ( `a` INTEGER NOT NULL );",
+			want:   diff.DataNarrowing,
+		},
+		{
+			name:   "int to int unsigned",
+			before: "CREATE TABLE `hoge` ( `a` INTEGER NOT NULL );",
+			after:  "CREATE TABLE `hoge` ( `a` INTEGER UNSIGNED NOT NULL );",
+			want:   diff.DataSignChange,
+		},
+		{
+			name:   "int unsigned to int",
+			before: "CREATE TABLE `hoge` ( `a` INTEGER UNSIGNED NOT NULL );",
+			after:  "CREATE TABLE `hoge` ( `a` INTEGER NOT NULL );",
+			want:   diff.DataSignChange,
+		},
+		{
+			name:   "int unsigned to bigint",
+			before: "CREATE TABLE `hoge` ( `a` INTEGER UNSIGNED NOT NULL );",
+			after:  "CREATE TABLE `hoge` ( `a` BIGINT NOT NULL );",
+			want:   diff.DataWidening,
+		},
+		{
+			name:   "widen decimal",
+			before: "CREATE TABLE `hoge` ( `a` DECIMAL (10, 2) NOT NULL );",
+			after:  "CREATE TABLE `hoge` ( `a` DECIMAL (12, 4) NOT NULL );",
+			want:   diff.DataWidening,
+		},
+		{
+			name:   "reduce the scale of decimal",
+			before: "CREATE TABLE `hoge` ( `a` DECIMAL (10, 2) NOT NULL );",
+			after:  "CREATE TABLE `hoge` ( `a` DECIMAL (10, 1) NOT NULL );",
+			want:   diff.DataNarrowing,
+		},
+		{
+			name:   "append enum value",
+			before: "CREATE TABLE `hoge` ( `a` ENUM ('x', 'y') NOT NULL );",
+			after:  "CREATE TABLE `hoge` ( `a` ENUM ('x', 'y', 'z') NOT NULL );",
+			want:   diff.DataWidening,
+		},
+		{
+			name:   "date to datetime",
+			before: "CREATE TABLE `hoge` ( `a` DATE NOT NULL );",
+			after:  "CREATE TABLE `hoge` ( `a` DATETIME NOT NULL );",
+			want:   diff.DataWidening,
+		},
+		{
+			name:   "convert character set",
+			before: "CREATE TABLE `hoge` ( `a` VARCHAR (255) CHARACTER SET latin1 NOT NULL );",
+			after:  "CREATE TABLE `hoge` ( `a` VARCHAR (255) CHARACTER SET utf8mb4 NOT NULL );",
+			want:   diff.DataCharsetChange,
+		},
+		{
+			name:   "change comment",
+			before: "CREATE TABLE `hoge` ( `a` VARCHAR (255) NOT NULL COMMENT 'a' );",
+			after:  "CREATE TABLE `hoge` ( `a` VARCHAR (255) NOT NULL COMMENT 'b' );",
+			want:   diff.DataLossless,
+		},
+		{
+			name:   "add NOT NULL",
+			before: "CREATE TABLE `hoge` ( `a` INTEGER );",
+			after:  "CREATE TABLE `hoge` ( `a` INTEGER NOT NULL );",
+			want:   diff.DataLossless,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := schemalex.New()
+			from, err := p.ParseString(tt.before)
+			if err != nil {
+				t.Fatal(err)
+			}
+			to, err := p.ParseString(tt.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stmts, err := diff.Diff(from, to)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			changes := diff.Classify(stmts)
+			if len(changes) != 1 {
+				t.Fatalf("want 1 change, got %d", len(changes))
+			}
+			if got := changes[0].DataLoss; got != tt.want {
+				t.Errorf("want %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseServerVersion(t *testing.T) {
+	tests := []struct {
+		input string
+		want  diff.ServerVersion
+	}{
+		{"8.0.32", diff.ServerVersion{Major: 8, Minor: 0, Patch: 32}},
+		{"5.7.44-log", diff.ServerVersion{Major: 5, Minor: 7, Patch: 44}},
+		{"8.0.36-0ubuntu0.22.04.1", diff.ServerVersion{Major: 8, Minor: 0, Patch: 36}},
+		{"8.4", diff.ServerVersion{Major: 8, Minor: 4}},
+	}
+	for _, tt := range tests {
+		got, err := diff.ParseServerVersion(tt.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q: want %v, got %v", tt.input, tt.want, got)
+		}
+	}
+
+	if _, err := diff.ParseServerVersion("unknown"); err == nil {
+		t.Error("want error, got nil")
+	}
+}
+
+func TestDiff_OnlineDDL(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		before  string
+		after   string
+		want    string
+	}{
+		{
+			name:    "add the last column instantly",
+			version: "8.0.12",
+			before:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			after:   "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, `b` INTEGER NOT NULL );",
+			want:    "ALTER TABLE `hoge` ADD COLUMN `b` INT (11) NOT NULL AFTER `a`, ALGORITHM=INSTANT;\n",
+		},
+		{
+			name:    "add a column in the middle in place",
+			version: "8.0.28",
+			before:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			after:   "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `b` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			want:    "ALTER TABLE `hoge` ADD COLUMN `b` INT (11) NOT NULL AFTER `id`, ALGORITHM=INPLACE, LOCK=NONE;\n",
+		},
+		{
+			name:    "add a column in the middle instantly",
+			version: "8.0.29",
+			before:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			after:   "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `b` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			want:    "ALTER TABLE `hoge` ADD COLUMN `b` INT (11) NOT NULL AFTER `id`, ALGORITHM=INSTANT;\n",
+		},
+		{
+			name:    "add the last column in place on MySQL 5.7",
+			version: "5.7.44",
+			before:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );",
+			after:   "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			want:    "ALTER TABLE `hoge` ADD COLUMN `a` INT (11) NOT NULL AFTER `id`, ALGORITHM=INPLACE, LOCK=NONE;\n",
+		},
+		{
+			name:    "add an index",
+			version: "8.0.32",
+			before:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			after:   "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, INDEX `ia` (`a`) );",
+			want:    "ALTER TABLE `hoge` ADD INDEX `ia` (`a`), ALGORITHM=INPLACE, LOCK=NONE;\n",
+		},
+		{
+			name:    "add a fulltext index",
+			version: "8.0.32",
+			before:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` TEXT NOT NULL );",
+			after:   "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` TEXT NOT NULL, FULLTEXT INDEX `ia` (`a`) );",
+			want:    "ALTER TABLE `hoge` ADD FULLTEXT INDEX `ia` (`a`), ALGORITHM=INPLACE, LOCK=SHARED;\n",
+		},
+		{
+			name:    "change the column type",
+			version: "8.0.32",
+			before:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );",
+			after:   "CREATE TABLE `hoge` ( `id` BIGINT NOT NULL );",
+			want:    "ALTER TABLE `hoge` CHANGE COLUMN `id` `id` BIGINT (20) NOT NULL, ALGORITHM=COPY, LOCK=SHARED;\n",
+		},
+		{
+			name:    "extend varchar",
+			version: "8.0.32",
+			before:  "CREATE TABLE `hoge` ( `a` VARCHAR (10) NOT NULL );",
+			after:   "CREATE TABLE `hoge` ( `a` VARCHAR (20) NOT NULL );",
+			want:    "ALTER TABLE `hoge` CHANGE COLUMN `a` `a` VARCHAR (20) NOT NULL, ALGORITHM=INPLACE, LOCK=NONE;\n",
+		},
+		{
+			name:    "extend varchar over 255 bytes",
+			version: "8.0.32",
+			before:  "CREATE TABLE `hoge` ( `a` VARCHAR (10) NOT NULL );",
+			after:   "CREATE TABLE `hoge` ( `a` VARCHAR (100) NOT NULL );",
+			want:    "ALTER TABLE `hoge` CHANGE COLUMN `a` `a` VARCHAR (100) NOT NULL, ALGORITHM=COPY, LOCK=SHARED;\n",
+		},
+		{
+			name:    "append an enum member",
+			version: "8.0.32",
+			before:  "CREATE TABLE `hoge` ( `a` ENUM ('x', 'y') NOT NULL );",
+			after:   "CREATE TABLE `hoge` ( `a` ENUM ('x', 'y', 'z') NOT NULL );",
+			want:    "ALTER TABLE `hoge` CHANGE COLUMN `a` `a` ENUM ('x','y','z') NOT NULL, ALGORITHM=INSTANT;\n",
+		},
+		{
+			name:    "append set members within 8 bytes",
+			version: "8.0.32",
+			before:  "CREATE TABLE `hoge` ( `a` SET ('m1', 'm2', 'm3', 'm4', 'm5', 'm6', 'm7', 'm8', 'm9', 'm10', 'm11', 'm12', 'm13', 'm14', 'm15', 'm16', 'm17', 'm18', 'm19', 'm20', 'm21', 'm22', 'm23', 'm24', 'm25', 'm26', 'm27', 'm28', 'm29', 'm30', 'm31', 'm32', 'm33') NOT NULL );",
+			after:   "CREATE TABLE `hoge` ( `a` SET ('m1', 'm2', 'm3', 'm4', 'm5', 'm6', 'm7', 'm8', 'm9', 'm10', 'm11', 'm12', 'm13', 'm14', 'm15', 'm16', 'm17', 'm18', 'm19', 'm20', 'm21', 'm22', 'm23', 'm24', 'm25', 'm26', 'm27', 'm28', 'm29', 'm30', 'm31', 'm32', 'm33', 'm34', 'm35', 'm36', 'm37', 'm38', 'm39', 'm40', 'm41', 'm42', 'm43', 'm44', 'm45', 'm46', 'm47', 'm48', 'm49', 'm50', 'm51', 'm52', 'm53', 'm54', 'm55', 'm56', 'm57', 'm58', 'm59', 'm60', 'm61', 'm62', 'm63', 'm64') NOT NULL );",
+			want:    "ALTER TABLE `hoge` CHANGE COLUMN `a` `a` SET ('m1','m2','m3','m4','m5','m6','m7','m8','m9','m10','m11','m12','m13','m14','m15','m16','m17','m18','m19','m20','m21','m22','m23','m24','m25','m26','m27','m28','m29','m30','m31','m32','m33','m34','m35','m36','m37','m38','m39','m40','m41','m42','m43','m44','m45','m46','m47','m48','m49','m50','m51','m52','m53','m54','m55','m56','m57','m58','m59','m60','m61','m62','m63','m64') NOT NULL, ALGORITHM=INSTANT;\n",
+		},
+		{
+			name:    "append set members over 4 bytes",
+			version: "8.0.32",
+			before:  "CREATE TABLE `hoge` ( `a` SET ('m1', 'm2', 'm3', 'm4', 'm5', 'm6', 'm7', 'm8', 'm9', 'm10', 'm11', 'm12', 'm13', 'm14', 'm15', 'm16', 'm17', 'm18', 'm19', 'm20', 'm21', 'm22', 'm23', 'm24', 'm25', 'm26', 'm27', 'm28', 'm29', 'm30', 'm31', 'm32') NOT NULL );",
+			after:   "CREATE TABLE `hoge` ( `a` SET ('m1', 'm2', 'm3', 'm4', 'm5', 'm6', 'm7', 'm8', 'm9', 'm10', 'm11', 'm12', 'm13', 'm14', 'm15', 'm16', 'm17', 'm18', 'm19', 'm20', 'm21', 'm22', 'm23', 'm24', 'm25', 'm26', 'm27', 'm28', 'm29', 'm30', 'm31', 'm32', 'm33') NOT NULL );",
+			want:    "ALTER TABLE `hoge` CHANGE COLUMN `a` `a` SET ('m1','m2','m3','m4','m5','m6','m7','m8','m9','m10','m11','m12','m13','m14','m15','m16','m17','m18','m19','m20','m21','m22','m23','m24','m25','m26','m27','m28','m29','m30','m31','m32','m33') NOT NULL, ALGORITHM=COPY, LOCK=SHARED;\n",
+		},
+		{
+			name:    "replace the primary key",
+			version: "8.0.32",
+			before:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, PRIMARY KEY (`id`) );",
+			after:   "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, PRIMARY KEY (`id`, `a`) );",
+			want:    "ALTER TABLE `hoge` DROP PRIMARY KEY, ADD PRIMARY KEY (`id`, `a`), ALGORITHM=INPLACE, LOCK=NONE;\n",
+		},
+		{
+			name:    "drop the primary key",
+			version: "8.0.32",
+			before:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) );",
+			after:   "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );",
+			want:    "ALTER TABLE `hoge` DROP PRIMARY KEY, ALGORITHM=COPY, LOCK=SHARED;\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := diff.Strings(&buf, tt.before, tt.after, diff.WithOnlineDDL(tt.version)); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, buf.String()); diff != "" {
+				t.Errorf("unexpected result (-want,+got):\n%s", diff)
+			}
+		})
+	}
+
+	if err := diff.Strings(&bytes.Buffer{}, "", "", diff.WithOnlineDDL("unknown")); err == nil {
+		t.Error("want error for the invalid version, got nil")
+	}
+}
+
+func TestDiff_AlterBatching(t *testing.T) {
+	const before = "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) );\n" +
+		"CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `fid` INTEGER NOT NULL, `b` INTEGER NOT NULL, PRIMARY KEY (`id`), CONSTRAINT `fk` FOREIGN KEY (`fid`) REFERENCES `fuga` (`id`) );"
+	const after = "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) );\n" +
+		"CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `fid` BIGINT NOT NULL, `b` INTEGER NOT NULL, `a` INTEGER NOT NULL, PRIMARY KEY (`id`), INDEX `ib` (`b`) );"
+
+	tests := []struct {
+		name     string
+		batching diff.AlterBatching
+		before   string
+		after    string
+		want     string
+	}{
+		{
+			name:     "all",
+			batching: diff.BatchAll,
+			before:   before,
+			after:    after,
+			want:     "ALTER TABLE `hoge` DROP FOREIGN KEY `fk`, DROP INDEX `fk`, ADD COLUMN `a` INT (11) NOT NULL AFTER `b`, CHANGE COLUMN `fid` `fid` BIGINT (20) NOT NULL, ADD INDEX `ib` (`b`), ALGORITHM=COPY, LOCK=SHARED;\n",
+		},
+		{
+			name:     "per clause",
+			batching: diff.BatchPerClause,
+			before:   before,
+			after:    after,
+			want: "ALTER TABLE `hoge` DROP FOREIGN KEY `fk`, ALGORITHM=INPLACE, LOCK=NONE;\n" +
+				"ALTER TABLE `hoge` DROP INDEX `fk`, ALGORITHM=INPLACE, LOCK=NONE;\n" +
+				"ALTER TABLE `hoge` ADD COLUMN `a` INT (11) NOT NULL AFTER `b`, ALGORITHM=INSTANT;\n" +
+				"ALTER TABLE `hoge` CHANGE COLUMN `fid` `fid` BIGINT (20) NOT NULL, ALGORITHM=COPY, LOCK=SHARED;\n" +
+				"ALTER TABLE `hoge` ADD INDEX `ib` (`b`), ALGORITHM=INPLACE, LOCK=NONE;\n",
+		},
+		{
+			name:     "by algorithm",
+			batching: diff.BatchByAlgorithm,
+			before:   before,
+			after:    after,
+			want: "ALTER TABLE `hoge` DROP FOREIGN KEY `fk`, DROP INDEX `fk`, ALGORITHM=INPLACE, LOCK=NONE;\n" +
+				"ALTER TABLE `hoge` ADD COLUMN `a` INT (11) NOT NULL AFTER `b`, ALGORITHM=INSTANT;\n" +
+				"ALTER TABLE `hoge` CHANGE COLUMN `fid` `fid` BIGINT (20) NOT NULL, ALGORITHM=COPY, LOCK=SHARED;\n" +
+				"ALTER TABLE `hoge` ADD INDEX `ib` (`b`), ALGORITHM=INPLACE, LOCK=NONE;\n",
+		},
+		{
+			name:     "keep the primary key and auto_increment together",
+			batching: diff.BatchPerClause,
+			before:   "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, PRIMARY KEY (`a`) );",
+			after:    "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL AUTO_INCREMENT, `a` INTEGER NOT NULL, `b` INTEGER NOT NULL, PRIMARY KEY (`id`) );",
+			want: "ALTER TABLE `hoge` ADD COLUMN `b` INT (11) NOT NULL AFTER `a`, ALGORITHM=INSTANT;\n" +
+				"ALTER TABLE `hoge` DROP PRIMARY KEY, ADD PRIMARY KEY (`id`), CHANGE COLUMN `id` `id` INT (11) NOT NULL AUTO_INCREMENT, ALGORITHM=COPY, LOCK=SHARED;\n",
+		},
+		{
+			name:     "replace the primary key with a new column",
+			batching: diff.BatchPerClause,
+			before:   "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `x` INTEGER, PRIMARY KEY (`id`) );",
+			after:    "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `x` INTEGER, `nid` INTEGER NOT NULL, PRIMARY KEY (`nid`) );",
+			want: "ALTER TABLE `hoge` ADD COLUMN `nid` INT (11) NOT NULL AFTER `x`, ALGORITHM=INSTANT;\n" +
+				"ALTER TABLE `hoge` DROP PRIMARY KEY, ADD PRIMARY KEY (`nid`), ALGORITHM=INPLACE, LOCK=NONE;\n",
+		},
+		{
+			name:     "replace the primary key with a new column by algorithm",
+			batching: diff.BatchByAlgorithm,
+			before:   "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `x` INTEGER, PRIMARY KEY (`id`) );",
+			after:    "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `x` INTEGER, `nid` INTEGER NOT NULL, PRIMARY KEY (`nid`) );",
+			want: "ALTER TABLE `hoge` ADD COLUMN `nid` INT (11) NOT NULL AFTER `x`, ALGORITHM=INSTANT;\n" +
+				"ALTER TABLE `hoge` DROP PRIMARY KEY, ADD PRIMARY KEY (`nid`), ALGORITHM=INPLACE, LOCK=NONE;\n",
+		},
+		{
+			name:     "keep the index on auto_increment column with the primary key",
+			batching: diff.BatchPerClause,
+			before:   "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL AUTO_INCREMENT, `a` INTEGER NOT NULL, PRIMARY KEY (`id`) );",
+			after:    "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL AUTO_INCREMENT, `a` INTEGER NOT NULL, PRIMARY KEY (`a`), INDEX `id` (`id`) );",
+			want:     "ALTER TABLE `hoge` DROP PRIMARY KEY, ADD PRIMARY KEY (`a`), ADD INDEX `id` (`id`), ALGORITHM=INPLACE, LOCK=NONE;\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := diff.Strings(&buf, tt.before, tt.after, diff.WithOnlineDDL("8.0.32"), diff.WithAlterBatching(tt.batching)); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, buf.String()); diff != "" {
+				t.Errorf("unexpected result (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestDiff_Integrated(t *testing.T) {
 	database.SkipIfNoTestDatabase(t)
 
@@ -612,9 +1818,8 @@ var autoSpecs = []AutoNamedSpec{
 			"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `fid` INTEGER NOT NULL, INDEX fid (fid) )",
 		},
 		Expect: []string{
-			"ALTER TABLE `fuga` " +
-				"DROP FOREIGN KEY `fuga_ibfk_1`, " +
-				"ADD INDEX `fid` (`fid`)",
+			// the index implicitly created for the foreign key is still alive.
+			"ALTER TABLE `fuga` DROP FOREIGN KEY `fuga_ibfk_1`",
 		},
 	},
 }
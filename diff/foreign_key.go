@@ -0,0 +1,126 @@
+package diff
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/shogo82148/schemalex-deploy/model"
+)
+
+// foreignKeyColumnAttributes is the attributes of the columns that MySQL doesn't allow to change
+// while foreign keys use the columns.
+var foreignKeyColumnAttributes = []string{
+	model.ColumnAttributeType,
+	model.ColumnAttributeLength,
+	model.ColumnAttributeEnumValues,
+	model.ColumnAttributeSetValues,
+	model.ColumnAttributeUnsigned,
+	model.ColumnAttributeZeroFill,
+	model.ColumnAttributeBinary,
+	model.ColumnAttributeCharacterSet,
+	model.ColumnAttributeCollation,
+}
+
+// dropModifiedForeignKeys drops the foreign keys whose referencing columns or referenced columns
+// change their types, because MySQL refuses to change the columns while the foreign keys use them.
+// alterTables adds them again after both columns are changed.
+// The foreign keys that are removed in the new schema and refer the unchanged columns
+// are dropped by the ALTER TABLE statement of their table as usual.
+func (ctx *diffCtx) dropModifiedForeignKeys() error {
+	ids := ctx.toSet.Intersect(ctx.fromSet)
+	tables, err := lookupTables(ctx.from, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		stmt, ok := ctx.to.Lookup(table.ID())
+		if !ok {
+			return fmt.Errorf("table not found in new schema: %q", table.ID())
+		}
+		to := stmt.(*model.Table)
+		for _, col := range to.Columns {
+			from, ok := table.LookupColumn(col.ID())
+			if !ok || !slices.ContainsFunc(from.Compare(col), func(attr string) bool {
+				return slices.Contains(foreignKeyColumnAttributes, attr)
+			}) {
+				continue
+			}
+			cols, ok := ctx.modifiedColumns[table.ID()]
+			if !ok {
+				cols = newSet()
+				ctx.modifiedColumns[table.ID()] = cols
+			}
+			cols.Add(col.ID())
+		}
+	}
+	if len(ctx.modifiedColumns) == 0 {
+		return nil
+	}
+
+	for _, table := range tables {
+		stmt, _ := ctx.to.Lookup(table.ID())
+		// name the unnamed foreign keys as MySQL does, so that we can drop them.
+		table = table.NameIndexes()
+		after := stmt.(*model.Table).NameIndexes()
+
+		var clauses []AlterClause
+		for _, fk := range table.ForeignKeys() {
+			if _, ok := ctx.droppedForeignKeys[table.ID()][fk.ID()]; ok {
+				continue
+			}
+			if !modifiesColumns(ctx.modifiedColumns, fk.Reference.TableID(), fk.Reference.Columns) {
+				if _, ok := after.LookupIndex(fk.ID()); !ok {
+					continue
+				}
+				if !modifiesColumns(ctx.modifiedColumns, table.ID(), fk.Columns) {
+					continue
+				}
+			}
+			name := getIndexName(fk)
+			if !name.Valid {
+				return fmt.Errorf("can not drop foreign key without name: %q", fk.ID())
+			}
+
+			clauses = append(clauses, &DropForeignKey{Index: fk, Name: name.Ident})
+
+			fks, ok := ctx.droppedForeignKeys[table.ID()]
+			if !ok {
+				fks = newSet()
+				ctx.droppedForeignKeys[table.ID()] = fks
+			}
+			fks.Add(fk.ID())
+		}
+		if len(clauses) > 0 {
+			ctx.append(&AlterTable{Table: table, Clauses: clauses})
+		}
+	}
+	return nil
+}
+
+// modifiesColumns reports whether some of the columns of the table change their types.
+func modifiesColumns(modified map[string]set, tableID string, columns []*model.IndexColumn) bool {
+	cols, ok := modified[tableID]
+	if !ok {
+		return false
+	}
+	for _, col := range columns {
+		if cols.Contains(model.NewTableColumn(string(col.Name)).ID()) {
+			return true
+		}
+	}
+	return false
+}
+
+// deferForeignKey reports whether the foreign key must be added after the referenced table is altered,
+// that is, the referenced columns change their types in a later statement.
+func (ctx *alterCtx) deferForeignKey(fk *model.Index) bool {
+	if fk.Reference == nil {
+		return false
+	}
+	ref := fk.Reference.TableID()
+	if ref == ctx.to.ID() || !ctx.pendingTables.Contains(ref) {
+		return false
+	}
+	return modifiesColumns(ctx.modifiedColumns, ref, fk.Reference.Columns)
+}
@@ -0,0 +1,176 @@
+package diff
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/shogo82148/schemalex-deploy/model"
+)
+
+// Ignore is the rules of the tables, the columns and the table options that are left untouched.
+//
+// The patterns of the tables and the columns are glob patterns, such as "_*_gho",
+// which are matched case-insensitively.
+// The patterns enclosed in slashes, such as "/^_.+_(gho|del)$/", are regular expressions.
+// The patterns of the columns are matched against "table.column".
+// The glob patterns without a dot match the columns of any table.
+type Ignore struct {
+	// Tables are the patterns of the names of the tables to ignore.
+	Tables []string
+
+	// Columns are the patterns of the names of the columns to ignore.
+	// The indexes on the ignored columns are also ignored.
+	Columns []string
+
+	// TableOptions are the keys of the table options to ignore, e.g. "AUTO_INCREMENT" and "COMMENT".
+	TableOptions []string
+}
+
+// ignoreFilter is the compiled rules of Ignore.
+type ignoreFilter struct {
+	tables       []*pattern
+	columns      []*pattern
+	tableOptions set
+}
+
+// pattern is a glob pattern or a regular expression.
+type pattern struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+func compilePattern(s string) (*pattern, error) {
+	if len(s) >= 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/") {
+		re, err := regexp.Compile(s[1 : len(s)-1])
+		if err != nil {
+			return nil, fmt.Errorf("diff: invalid pattern %q: %w", s, err)
+		}
+		return &pattern{re: re}, nil
+	}
+	glob := strings.ToLower(s)
+	if _, err := path.Match(glob, ""); err != nil {
+		return nil, fmt.Errorf("diff: invalid pattern %q: %w", s, err)
+	}
+	return &pattern{glob: glob}, nil
+}
+
+func (p *pattern) match(s string) bool {
+	if p.re != nil {
+		return p.re.MatchString(s)
+	}
+	ok, _ := path.Match(p.glob, strings.ToLower(s))
+	return ok
+}
+
+func (ig *Ignore) compile() (*ignoreFilter, error) {
+	f := &ignoreFilter{
+		tableOptions: newSet(),
+	}
+	for _, s := range ig.Tables {
+		p, err := compilePattern(s)
+		if err != nil {
+			return nil, err
+		}
+		f.tables = append(f.tables, p)
+	}
+	for _, s := range ig.Columns {
+		if !strings.HasPrefix(s, "/") && !strings.Contains(s, ".") {
+			s = "*." + s
+		}
+		p, err := compilePattern(s)
+		if err != nil {
+			return nil, err
+		}
+		f.columns = append(f.columns, p)
+	}
+	for _, key := range ig.TableOptions {
+		f.tableOptions.Add(normalizeTableOptionKey(key))
+	}
+	return f, nil
+}
+
+// IgnoresTable reports whether the table is ignored.
+func (ig *Ignore) IgnoresTable(name string) (bool, error) {
+	f, err := ig.compile()
+	if err != nil {
+		return false, err
+	}
+	return f.ignoresTable(name), nil
+}
+
+// Filter returns the statements without the ignored tables, columns and table options.
+// The statements that have nothing to ignore are returned as is.
+func (ig *Ignore) Filter(stmts model.Stmts) (model.Stmts, error) {
+	f, err := ig.compile()
+	if err != nil {
+		return nil, err
+	}
+	ret := make(model.Stmts, 0, len(stmts))
+	for _, stmt := range stmts {
+		table, ok := stmt.(*model.Table)
+		if !ok {
+			ret = append(ret, stmt)
+			continue
+		}
+		if f.ignoresTable(string(table.Name)) {
+			continue
+		}
+		ret = append(ret, f.filterTable(table))
+	}
+	return ret, nil
+}
+
+func (f *ignoreFilter) ignoresTable(name string) bool {
+	for _, p := range f.tables {
+		if p.match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *ignoreFilter) ignoresColumn(table, column string) bool {
+	for _, p := range f.columns {
+		if p.match(table + "." + column) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterTable returns a copy of the table without the ignored columns and table options,
+// or the table itself if there is nothing to ignore.
+func (f *ignoreFilter) filterTable(table *model.Table) *model.Table {
+	ignored := newSet()
+	for _, col := range table.Columns {
+		if f.ignoresColumn(string(table.Name), string(col.Name)) {
+			ignored.Add(strings.ToLower(string(col.Name)))
+		}
+	}
+	var ignoresOption bool
+	for _, opt := range table.Options {
+		if f.tableOptions.Contains(normalizeTableOptionKey(opt.Key)) {
+			ignoresOption = true
+		}
+	}
+	if ignored.Cardinality() == 0 && !ignoresOption {
+		return table
+	}
+
+	newTable := table.Clone()
+	newTable.Columns = slices.DeleteFunc(newTable.Columns, func(col *model.TableColumn) bool {
+		return ignored.Contains(strings.ToLower(string(col.Name)))
+	})
+	newTable.Indexes = slices.DeleteFunc(newTable.Indexes, func(idx *model.Index) bool {
+		return slices.ContainsFunc(idx.Columns, func(col *model.IndexColumn) bool {
+			return ignored.Contains(strings.ToLower(string(col.Name)))
+		})
+	})
+	newTable.Options = slices.DeleteFunc(newTable.Options, func(opt *model.TableOption) bool {
+		return f.tableOptions.Contains(normalizeTableOptionKey(opt.Key))
+	})
+	return newTable
+}
@@ -13,6 +13,15 @@ func (s set) Add(item string) {
 	s[item] = struct{}{}
 }
 
+func (s set) Remove(item string) {
+	delete(s, item)
+}
+
+func (s set) Contains(item string) bool {
+	_, ok := s[item]
+	return ok
+}
+
 func (s set) Difference(t set) set {
 	u := newSet()
 	for item := range s {
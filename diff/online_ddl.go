@@ -0,0 +1,335 @@
+package diff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/shogo82148/schemalex-deploy/model"
+)
+
+// Algorithm is the algorithm that MySQL uses to run ALTER TABLE.
+type Algorithm int
+
+// List of algorithms, from the cheapest to the most expensive.
+const (
+	// AlgorithmDefault lets MySQL choose the algorithm.
+	AlgorithmDefault Algorithm = iota
+
+	// AlgorithmInstant changes only the metadata of the table.
+	AlgorithmInstant
+
+	// AlgorithmInplace changes the table without copying it, and permits concurrent DML in most cases.
+	AlgorithmInplace
+
+	// AlgorithmCopy copies the table, and blocks concurrent DML.
+	AlgorithmCopy
+)
+
+func (a Algorithm) String() string {
+	switch a {
+	case AlgorithmDefault:
+		return "DEFAULT"
+	case AlgorithmInstant:
+		return "INSTANT"
+	case AlgorithmInplace:
+		return "INPLACE"
+	case AlgorithmCopy:
+		return "COPY"
+	}
+	return "Algorithm(" + strconv.Itoa(int(a)) + ")"
+}
+
+// Lock is the level of the lock that MySQL takes while it runs ALTER TABLE.
+type Lock int
+
+// List of lock levels, from the weakest to the strongest.
+const (
+	// LockDefault lets MySQL choose the lock level.
+	LockDefault Lock = iota
+
+	// LockNone permits concurrent reads and writes.
+	LockNone
+
+	// LockShared permits concurrent reads, and blocks writes.
+	LockShared
+
+	// LockExclusive blocks concurrent reads and writes.
+	LockExclusive
+)
+
+func (l Lock) String() string {
+	switch l {
+	case LockDefault:
+		return "DEFAULT"
+	case LockNone:
+		return "NONE"
+	case LockShared:
+		return "SHARED"
+	case LockExclusive:
+		return "EXCLUSIVE"
+	}
+	return "Lock(" + strconv.Itoa(int(l)) + ")"
+}
+
+// ServerVersion is the version of MySQL server.
+type ServerVersion struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseServerVersion parses the version of MySQL server, such as "8.0.32" and "5.7.44-log".
+func ParseServerVersion(s string) (ServerVersion, error) {
+	// remove the suffix, e.g. "-log" and "-0ubuntu0.22.04.1"
+	if i := strings.IndexFunc(s, func(r rune) bool { return r != '.' && (r < '0' || r > '9') }); i >= 0 {
+		s = s[:i]
+	}
+
+	var v ServerVersion
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return ServerVersion{}, fmt.Errorf("diff: invalid server version: %q", s)
+	}
+	nums := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return ServerVersion{}, fmt.Errorf("diff: invalid server version: %q", s)
+		}
+		*nums[i] = n
+	}
+	return v, nil
+}
+
+func (v ServerVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast reports whether the version is major.minor.patch or later.
+func (v ServerVersion) AtLeast(major, minor, patch int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+	if v.Minor != minor {
+		return v.Minor > minor
+	}
+	return v.Patch >= patch
+}
+
+// OnlineDDL is the cheapest algorithm and the weakest lock level that MySQL can run the change with.
+type OnlineDDL struct {
+	Algorithm Algorithm
+	Lock      Lock
+}
+
+func (o OnlineDDL) String() string {
+	if o.Algorithm == AlgorithmInstant {
+		// MySQL rejects LOCK clauses except LOCK=DEFAULT with ALGORITHM=INSTANT.
+		return "ALGORITHM=INSTANT"
+	}
+	return "ALGORITHM=" + o.Algorithm.String() + ", LOCK=" + o.Lock.String()
+}
+
+// merge returns the online DDL that runs both o and p.
+func (o OnlineDDL) merge(p OnlineDDL) OnlineDDL {
+	return OnlineDDL{
+		Algorithm: max(o.Algorithm, p.Algorithm),
+		Lock:      max(o.Lock, p.Lock),
+	}
+}
+
+var (
+	onlineInstant = OnlineDDL{Algorithm: AlgorithmInstant, Lock: LockNone}
+	onlineInplace = OnlineDDL{Algorithm: AlgorithmInplace, Lock: LockNone}
+	onlineShared  = OnlineDDL{Algorithm: AlgorithmInplace, Lock: LockShared}
+	onlineCopy    = OnlineDDL{Algorithm: AlgorithmCopy, Lock: LockShared}
+)
+
+// PredictOnlineDDL predicts the cheapest algorithm and the weakest lock level
+// that the server of the version can run the statement with,
+// according to the online DDL operations described in the MySQL reference manual.
+// It assumes that foreign_key_checks is disabled, as the deploy package does.
+func (s *AlterTable) PredictOnlineDDL(version ServerVersion) OnlineDDL {
+	var addsPrimaryKey bool
+	for _, c := range s.Clauses {
+		if c, ok := c.(*AddIndex); ok && c.Index.Kind == model.IndexKindPrimaryKey {
+			addsPrimaryKey = true
+		}
+	}
+
+	ret := onlineInstant
+	for _, c := range s.Clauses {
+		o := predictClause(s.Table, c, version)
+		if c, ok := c.(*DropIndex); ok && c.Index.Kind == model.IndexKindPrimaryKey && addsPrimaryKey {
+			// dropping the primary key requires copying the table, unless another one is added at the same time.
+			o = onlineInplace
+		}
+		ret = ret.merge(o)
+	}
+	if !version.AtLeast(8, 0, 12) && ret.Algorithm == AlgorithmInstant {
+		ret.Algorithm = AlgorithmInplace
+	}
+	if !version.AtLeast(5, 6, 0) {
+		// online DDL is not supported.
+		ret = onlineCopy
+	}
+	return ret
+}
+
+func predictClause(table *model.Table, clause AlterClause, version ServerVersion) OnlineDDL {
+	switch c := clause.(type) {
+	case *AddColumn:
+		if c.Column.AutoIncrement {
+			return onlineCopy
+		}
+		if version.AtLeast(8, 0, 29) {
+			return onlineInstant
+		}
+		if version.AtLeast(8, 0, 12) && isLastColumn(table, c.Column) {
+			return onlineInstant
+		}
+		return onlineInplace
+	case *DropColumn:
+		if version.AtLeast(8, 0, 29) {
+			return onlineInstant
+		}
+		return onlineInplace
+	case *RenameColumn:
+		if version.AtLeast(8, 0, 28) {
+			return onlineInstant
+		}
+		return onlineInplace
+	case *ChangeColumn:
+		return predictChangeColumn(table, c, version)
+	case *AddIndex:
+		switch c.Index.Kind {
+		case model.IndexKindFullText, model.IndexKindSpatial:
+			return onlineShared
+		}
+		return onlineInplace
+	case *DropIndex:
+		if c.Index.Kind == model.IndexKindPrimaryKey {
+			return onlineCopy
+		}
+		return onlineInplace
+	case *RenameIndex:
+		return onlineInstant
+	case *DropForeignKey:
+		return onlineInplace
+	case *ConvertCharset:
+		// converting the character set copies the table.
+		return onlineCopy
+	case *SetTableOption:
+		if normalizeTableOptionKey(c.Option.Key) == "ENGINE" && !strings.EqualFold(c.Option.Value, "InnoDB") {
+			return onlineCopy
+		}
+		return onlineInplace
+	}
+	return onlineCopy
+}
+
+func predictChangeColumn(table *model.Table, c *ChangeColumn, version ServerVersion) OnlineDDL {
+	from, to := c.From, c.To
+
+	var metadata, inplace, rebuild, copies bool
+	for _, attr := range from.Compare(to) {
+		switch attr {
+		case model.ColumnAttributeDefault, model.ColumnAttributeComment:
+			metadata = true
+		case model.ColumnAttributeName:
+			if !version.AtLeast(8, 0, 28) {
+				rebuild = true
+			}
+		case model.ColumnAttributeNullState:
+			rebuild = true
+		case model.ColumnAttributeEnumValues, model.ColumnAttributeSetValues:
+			if !appendsMembers(from, to) {
+				copies = true
+			}
+		case model.ColumnAttributeLength:
+			if extendsVarChar(table, from, to) {
+				// MySQL extends VARCHAR in place by modifying the metadata, but not instantly.
+				inplace = true
+			} else {
+				copies = true
+			}
+		default:
+			copies = true
+		}
+	}
+	if c.Position != nil {
+		// reordering the columns rebuilds the table.
+		rebuild = true
+	}
+
+	switch {
+	case copies:
+		return onlineCopy
+	case rebuild, inplace:
+		return onlineInplace
+	case metadata && !version.AtLeast(8, 0, 12):
+		return onlineInplace
+	}
+	return onlineInstant
+}
+
+// isLastColumn reports whether the column is the last column of the table.
+func isLastColumn(table *model.Table, col *model.TableColumn) bool {
+	n := len(table.Columns)
+	return n > 0 && table.Columns[n-1].ID() == col.ID()
+}
+
+// appendsMembers reports whether the ENUM or SET column just appends the members to the end,
+// without changing the storage size.
+func appendsMembers(from, to *model.TableColumn) bool {
+	if from.Type != to.Type {
+		return false
+	}
+	fromValues, toValues := from.EnumValues, to.EnumValues
+	storageSize := enumStorageSize
+	if from.Type == model.ColumnTypeSet {
+		fromValues, toValues = from.SetValues, to.SetValues
+		storageSize = setStorageSize
+	}
+	if len(toValues) < len(fromValues) {
+		return false
+	}
+	for i, v := range fromValues {
+		if toValues[i] != v {
+			return false
+		}
+	}
+	return storageSize(len(fromValues)) == storageSize(len(toValues))
+}
+
+// enumStorageSize returns the bytes to store the ENUM value with n members.
+func enumStorageSize(n int) int {
+	if n <= 255 {
+		return 1
+	}
+	return 2
+}
+
+// setStorageSize returns the bytes to store the SET value with n members,
+// that is, 1, 2, 3, 4 or 8 bytes.
+func setStorageSize(n int) int {
+	size := (n + 7) / 8
+	if size > 4 {
+		return 8
+	}
+	return size
+}
+
+// extendsVarChar reports whether the VARCHAR column is extended without changing the number of the length bytes.
+func extendsVarChar(table *model.Table, from, to *model.TableColumn) bool {
+	if from.Type != model.ColumnTypeVarChar || to.Type != model.ColumnTypeVarChar {
+		return false
+	}
+	fromLen, toLen := lengthOf(from, 0), lengthOf(to, 0)
+	if toLen < fromLen {
+		return false
+	}
+	n := model.CharsetMaxLen(table.ColumnCharset(to))
+	return (fromLen*n < 256) == (toLen*n < 256)
+}
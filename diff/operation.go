@@ -0,0 +1,350 @@
+package diff
+
+import (
+	"strings"
+
+	"github.com/shogo82148/schemalex-deploy/format"
+	"github.com/shogo82148/schemalex-deploy/model"
+)
+
+// renderer is implemented by the statements and the clauses that are rendered from the models.
+type renderer interface {
+	render(w *strings.Builder) error
+}
+
+// renderString renders r, ignoring the error.
+// Diff reports the errors by rendering the statements before returning them.
+func renderString(r renderer) string {
+	var buf strings.Builder
+	r.render(&buf)
+	return buf.String()
+}
+
+// CreateTable is a CREATE TABLE statement.
+type CreateTable struct {
+	// Table is the table to create.
+	Table *model.Table
+
+	// Indent is the string used for indenting the definitions.
+	Indent string
+}
+
+func (s *CreateTable) String() string {
+	return renderString(s)
+}
+
+func (s *CreateTable) render(w *strings.Builder) error {
+	return format.SQL(w, s.Table, format.WithIndent(s.Indent, 1))
+}
+
+// DropTable is a DROP TABLE statement.
+type DropTable struct {
+	// Table is the table to drop.
+	Table *model.Table
+}
+
+func (s *DropTable) String() string {
+	return renderString(s)
+}
+
+func (s *DropTable) render(w *strings.Builder) error {
+	w.WriteString("DROP TABLE ")
+	w.WriteString(s.Table.Name.Quoted())
+	return nil
+}
+
+// RenameTable is a RENAME TABLE statement.
+type RenameTable struct {
+	// Table is the table in the old schema.
+	Table *model.Table
+
+	// Name is the new name of the table.
+	Name model.Ident
+}
+
+func (s *RenameTable) String() string {
+	return renderString(s)
+}
+
+func (s *RenameTable) render(w *strings.Builder) error {
+	w.WriteString("RENAME TABLE ")
+	w.WriteString(s.Table.Name.Quoted())
+	w.WriteString(" TO ")
+	w.WriteString(s.Name.Quoted())
+	return nil
+}
+
+// AlterTable is an ALTER TABLE statement.
+type AlterTable struct {
+	// Table is the table to alter.
+	Table *model.Table
+
+	// Clauses are the alter specifications, which are applied in order.
+	Clauses []AlterClause
+
+	// OnlineDDL is the algorithm and the lock level appended to the statement.
+	// The zero value appends nothing, and lets MySQL choose them.
+	OnlineDDL OnlineDDL
+}
+
+func (s *AlterTable) String() string {
+	return renderString(s)
+}
+
+func (s *AlterTable) render(w *strings.Builder) error {
+	w.WriteString("ALTER TABLE ")
+	w.WriteString(s.Table.Name.Quoted())
+	w.WriteString(" ")
+	for i, c := range s.Clauses {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		if err := c.render(w); err != nil {
+			return err
+		}
+	}
+	if s.OnlineDDL.Algorithm != AlgorithmDefault {
+		w.WriteString(", ")
+		w.WriteString(s.OnlineDDL.String())
+	}
+	return nil
+}
+
+// AlterClause is an alter specification of ALTER TABLE.
+type AlterClause interface {
+	// String returns the SQL text of the clause.
+	String() string
+
+	renderer
+}
+
+// ColumnPosition is the position of a column, that is FIRST or AFTER the column.
+type ColumnPosition struct {
+	// After is the column that the column is placed after.
+	// nil means the first column.
+	After *model.TableColumn
+}
+
+func (p *ColumnPosition) render(w *strings.Builder) {
+	if p == nil {
+		return
+	}
+	if p.After == nil {
+		w.WriteString(" FIRST")
+		return
+	}
+	w.WriteString(" AFTER ")
+	w.WriteString(p.After.Name.Quoted())
+}
+
+// AddColumn is an ADD COLUMN clause.
+type AddColumn struct {
+	// Column is the column to add.
+	Column *model.TableColumn
+
+	// Position is the position of the column. nil means the last.
+	Position *ColumnPosition
+}
+
+func (c *AddColumn) String() string {
+	return renderString(c)
+}
+
+func (c *AddColumn) render(w *strings.Builder) error {
+	w.WriteString("ADD COLUMN ")
+	if err := format.SQL(w, c.Column); err != nil {
+		return err
+	}
+	c.Position.render(w)
+	return nil
+}
+
+// DropColumn is a DROP COLUMN clause.
+type DropColumn struct {
+	// Column is the column to drop.
+	Column *model.TableColumn
+}
+
+func (c *DropColumn) String() string {
+	return renderString(c)
+}
+
+func (c *DropColumn) render(w *strings.Builder) error {
+	w.WriteString("DROP COLUMN ")
+	w.WriteString(c.Column.Name.Quoted())
+	return nil
+}
+
+// ChangeColumn is a CHANGE COLUMN clause.
+// It is rendered as MODIFY COLUMN if only the position is changed.
+type ChangeColumn struct {
+	// From is the column in the old schema.
+	From *model.TableColumn
+
+	// To is the new definition of the column.
+	To *model.TableColumn
+
+	// Position is the new position of the column. nil means the column stays.
+	Position *ColumnPosition
+}
+
+func (c *ChangeColumn) String() string {
+	return renderString(c)
+}
+
+func (c *ChangeColumn) render(w *strings.Builder) error {
+	if c.From.Equal(c.To) {
+		w.WriteString("MODIFY COLUMN ")
+	} else {
+		w.WriteString("CHANGE COLUMN ")
+		w.WriteString(c.From.Name.Quoted())
+		w.WriteString(" ")
+	}
+	if err := format.SQL(w, c.To); err != nil {
+		return err
+	}
+	c.Position.render(w)
+	return nil
+}
+
+// RenameColumn is a RENAME COLUMN clause.
+type RenameColumn struct {
+	// From is the column in the old schema.
+	From *model.TableColumn
+
+	// To is the column in the new schema.
+	To *model.TableColumn
+}
+
+func (c *RenameColumn) String() string {
+	return renderString(c)
+}
+
+func (c *RenameColumn) render(w *strings.Builder) error {
+	w.WriteString("RENAME COLUMN ")
+	w.WriteString(c.From.Name.Quoted())
+	w.WriteString(" TO ")
+	w.WriteString(c.To.Name.Quoted())
+	return nil
+}
+
+// AddIndex is an ADD clause of an index, including PRIMARY KEY and FOREIGN KEY.
+type AddIndex struct {
+	// Index is the index to add.
+	Index *model.Index
+}
+
+func (c *AddIndex) String() string {
+	return renderString(c)
+}
+
+func (c *AddIndex) render(w *strings.Builder) error {
+	w.WriteString("ADD ")
+	return format.SQL(w, c.Index)
+}
+
+// DropIndex is a DROP INDEX clause, or DROP PRIMARY KEY for the primary key.
+type DropIndex struct {
+	// Index is the index to drop.
+	Index *model.Index
+
+	// Name is the name of the index in the database.
+	Name model.Ident
+}
+
+func (c *DropIndex) String() string {
+	return renderString(c)
+}
+
+func (c *DropIndex) render(w *strings.Builder) error {
+	if c.Index.Kind == model.IndexKindPrimaryKey {
+		w.WriteString("DROP PRIMARY KEY")
+		return nil
+	}
+	w.WriteString("DROP INDEX ")
+	w.WriteString(c.Name.Quoted())
+	return nil
+}
+
+// DropForeignKey is a DROP FOREIGN KEY clause.
+// The index that MySQL has created for the foreign key is not dropped.
+type DropForeignKey struct {
+	// Index is the foreign key to drop.
+	Index *model.Index
+
+	// Name is the symbol of the foreign key in the database.
+	Name model.Ident
+}
+
+func (c *DropForeignKey) String() string {
+	return renderString(c)
+}
+
+func (c *DropForeignKey) render(w *strings.Builder) error {
+	w.WriteString("DROP FOREIGN KEY ")
+	w.WriteString(c.Name.Quoted())
+	return nil
+}
+
+// RenameIndex is a RENAME INDEX clause.
+type RenameIndex struct {
+	// From is the index in the old schema.
+	From *model.Index
+
+	// To is the index in the new schema.
+	To *model.Index
+
+	// Name is the name of the index in the database.
+	Name model.Ident
+}
+
+func (c *RenameIndex) String() string {
+	return renderString(c)
+}
+
+func (c *RenameIndex) render(w *strings.Builder) error {
+	w.WriteString("RENAME INDEX ")
+	w.WriteString(c.Name.Quoted())
+	w.WriteString(" TO ")
+	w.WriteString(c.To.Name.Ident.Quoted())
+	return nil
+}
+
+// SetTableOption is a table option clause, such as ENGINE = InnoDB.
+type SetTableOption struct {
+	// Option is the new value of the option.
+	Option *model.TableOption
+}
+
+func (c *SetTableOption) String() string {
+	return renderString(c)
+}
+
+func (c *SetTableOption) render(w *strings.Builder) error {
+	return format.SQL(w, c.Option)
+}
+
+// ConvertCharset is a CONVERT TO CHARACTER SET clause.
+// It converts all the character columns, and changes the default character set of the table.
+type ConvertCharset struct {
+	// Charset is the new character set.
+	Charset model.Ident
+
+	// Collation is the new collation.
+	// Empty means the default collation of the character set.
+	Collation model.Ident
+}
+
+func (c *ConvertCharset) String() string {
+	return renderString(c)
+}
+
+func (c *ConvertCharset) render(w *strings.Builder) error {
+	w.WriteString("CONVERT TO CHARACTER SET ")
+	w.WriteString(string(c.Charset))
+	if c.Collation != "" {
+		w.WriteString(" COLLATE ")
+		w.WriteString(string(c.Collation))
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	"github.com/shogo82148/schemalex-deploy"
+	"github.com/shogo82148/schemalex-deploy/model"
 )
 
 type myOptions struct {
@@ -11,6 +12,22 @@ type myOptions struct {
 	transaction   bool
 	currentSchema string
 	indent        string
+
+	// ignoredTableOptions is the list of the table options that are not compared.
+	// nil means DefaultIgnoredTableOptions.
+	ignoredTableOptions []string
+
+	noColumnReordering bool
+	columnReordering   map[string]bool
+
+	// onlineDDL is the version of the server to predict the online DDL for. empty means disabled.
+	onlineDDL string
+
+	// alterBatching is the strategy to batch the clauses of ALTER TABLE statements.
+	alterBatching AlterBatching
+
+	// ignore is the rules of the tables, the columns and the table options that are not compared.
+	ignore *Ignore
 }
 
 type Option interface {
@@ -73,3 +90,90 @@ func WithIndent(s string, n int) Option {
 	}
 	return withIndent(strings.Repeat(s, n))
 }
+
+type withIgnoredTableOptions []string
+
+func (opt withIgnoredTableOptions) apply(opts *myOptions) {
+	opts.ignoredTableOptions = []string(opt)
+}
+
+// WithIgnoredTableOptions specifies the table options that are not compared,
+// e.g. "AUTO_INCREMENT", "COMMENT", and "CHARSET".
+// It replaces DefaultIgnoredTableOptions, which is used if unspecified.
+func WithIgnoredTableOptions(keys ...string) Option {
+	// keep it non-nil to distinguish from the default.
+	return withIgnoredTableOptions(append([]string{}, keys...))
+}
+
+type withColumnReordering struct {
+	enabled bool
+	tables  []string
+}
+
+func (opt withColumnReordering) apply(opts *myOptions) {
+	if len(opt.tables) == 0 {
+		opts.noColumnReordering = !opt.enabled
+		return
+	}
+	if opts.columnReordering == nil {
+		opts.columnReordering = make(map[string]bool)
+	}
+	for _, table := range opt.tables {
+		opts.columnReordering[model.NewTable(model.Ident(table)).ID()] = opt.enabled
+	}
+}
+
+// WithColumnReordering specifies whether the existing columns are moved
+// to the positions in the new schema. It is enabled by default.
+// Moving columns rebuilds the table, so you may want to disable it on large tables.
+// If tables are given, the setting applies only to them.
+func WithColumnReordering(enabled bool, tables ...string) Option {
+	return withColumnReordering{
+		enabled: enabled,
+		tables:  tables,
+	}
+}
+
+type withOnlineDDL string
+
+func (opt withOnlineDDL) apply(opts *myOptions) {
+	opts.onlineDDL = string(opt)
+}
+
+// WithOnlineDDL appends ALGORITHM and LOCK clauses to each ALTER TABLE statement.
+// They are the cheapest ones that the MySQL server of the version, e.g. "8.0.32", can run the statement with,
+// so that the server fails fast instead of running the statement in a more expensive way,
+// such as copying the whole table, if the prediction is wrong.
+// An empty version disables it.
+func WithOnlineDDL(version string) Option {
+	return withOnlineDDL(version)
+}
+
+type withAlterBatching AlterBatching
+
+func (opt withAlterBatching) apply(opts *myOptions) {
+	opts.alterBatching = AlterBatching(opt)
+}
+
+// WithAlterBatching specifies the strategy to batch the changes of a table into ALTER TABLE statements.
+// The default is BatchAll.
+// BatchByAlgorithm uses the server version given by WithOnlineDDL to predict the algorithms,
+// or assumes the latest version of MySQL.
+func WithAlterBatching(batching AlterBatching) Option {
+	return withAlterBatching(batching)
+}
+
+type withIgnore struct {
+	ignore *Ignore
+}
+
+func (opt withIgnore) apply(opts *myOptions) {
+	opts.ignore = opt.ignore
+}
+
+// WithIgnore specifies the tables, the columns and the table options that are not compared,
+// such as the leftovers of online schema change tools.
+// They are neither created, altered nor dropped.
+func WithIgnore(ignore *Ignore) Option {
+	return withIgnore{ignore}
+}
@@ -0,0 +1,196 @@
+package diff
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/shogo82148/schemalex-deploy/model"
+)
+
+// Risk is the impact of a change on the running database.
+type Risk int
+
+// List of risks, from the least to the most severe.
+const (
+	// RiskSafe means the change neither loses any data nor blocks the writes for long.
+	RiskSafe Risk = iota
+
+	// RiskBlocking means the change may rebuild the table, and block the writes while it runs.
+	RiskBlocking
+
+	// RiskDestructive means the change may lose data or fail on the existing rows,
+	// e.g. dropping tables and columns, narrowing column types,
+	// converting character sets and adding NOT NULL constraints.
+	RiskDestructive
+)
+
+func (r Risk) String() string {
+	switch r {
+	case RiskSafe:
+		return "safe"
+	case RiskBlocking:
+		return "blocking"
+	case RiskDestructive:
+		return "destructive"
+	}
+	return "Risk(" + strconv.Itoa(int(r)) + ")"
+}
+
+// Change is a change in the schema made by a statement, and its risk.
+type Change struct {
+	// Stmt is the statement that makes the change.
+	Stmt Stmt
+
+	// Clause is the clause of ALTER TABLE that makes the change.
+	// It is nil if the change is made by the whole statement, such as CREATE TABLE.
+	Clause AlterClause
+
+	// Table is the name of the changed table.
+	// It is empty for the statements that are not related to tables, such as BEGIN.
+	Table model.Ident
+
+	// Risk is the risk of the change.
+	Risk Risk
+
+	// Reason describes why the change is risky.
+	// It is empty for the safe changes.
+	Reason string
+
+	// DataLoss is the kind of the change of the column type.
+	// It is DataLossless for the changes other than CHANGE COLUMN and CONVERT TO CHARACTER SET.
+	DataLoss DataLoss
+}
+
+func (c *Change) String() string {
+	if c.Reason == "" {
+		return c.Risk.String()
+	}
+	return c.Risk.String() + ": " + c.Reason
+}
+
+// Classify classifies the changes made by the statements by their risks.
+// It returns a change for each statement, or each clause of ALTER TABLE statements.
+func Classify(stmts Stmts) []*Change {
+	var changes []*Change
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *CreateTable:
+			changes = append(changes, &Change{Stmt: stmt, Table: stmt.Table.Name, Risk: RiskSafe})
+		case *DropTable:
+			changes = append(changes, &Change{
+				Stmt:   stmt,
+				Table:  stmt.Table.Name,
+				Risk:   RiskDestructive,
+				Reason: "drops table " + stmt.Table.Name.Quoted(),
+			})
+		case *RenameTable:
+			changes = append(changes, &Change{Stmt: stmt, Table: stmt.Table.Name, Risk: RiskSafe})
+		case *AlterTable:
+			for _, clause := range stmt.Clauses {
+				risk, reason := classifyClause(stmt.Table, clause)
+				var loss DataLoss
+				switch c := clause.(type) {
+				case *ChangeColumn:
+					loss = c.DataLoss(stmt.Table)
+				case *ConvertCharset:
+					loss = DataCharsetChange
+				}
+				changes = append(changes, &Change{
+					Stmt:     stmt,
+					Clause:   clause,
+					Table:    stmt.Table.Name,
+					Risk:     risk,
+					Reason:   reason,
+					DataLoss: loss,
+				})
+			}
+		default:
+			changes = append(changes, &Change{Stmt: stmt, Risk: RiskSafe})
+		}
+	}
+	return changes
+}
+
+// MaxRisk returns the most severe risk of the changes.
+func MaxRisk(changes []*Change) Risk {
+	risk := RiskSafe
+	for _, c := range changes {
+		risk = max(risk, c.Risk)
+	}
+	return risk
+}
+
+func classifyClause(table *model.Table, clause AlterClause) (Risk, string) {
+	switch c := clause.(type) {
+	case *DropColumn:
+		return RiskDestructive, "drops column " + c.Column.Name.Quoted()
+	case *ChangeColumn:
+		return classifyChangeColumn(table, c)
+	case *AddIndex:
+		if c.Index.Kind == model.IndexKindPrimaryKey {
+			return RiskBlocking, "rebuilds the table to add the primary key"
+		}
+	case *DropIndex:
+		if c.Index.Kind == model.IndexKindPrimaryKey {
+			return RiskBlocking, "rebuilds the table to drop the primary key"
+		}
+	case *ConvertCharset:
+		return RiskDestructive, "converts table " + table.Name.Quoted() + " to character set " + string(c.Charset)
+	case *SetTableOption:
+		switch normalizeTableOptionKey(c.Option.Key) {
+		case "ENGINE", "ROW_FORMAT", "KEY_BLOCK_SIZE":
+			return RiskBlocking, "rebuilds the table to change " + c.Option.Key
+		}
+	}
+	return RiskSafe, ""
+}
+
+func classifyChangeColumn(table *model.Table, c *ChangeColumn) (Risk, string) {
+	name := c.To.Name.Quoted()
+	from, to := c.From, c.To
+	loss := c.DataLoss(table)
+	if loss == DataCharsetChange {
+		return RiskDestructive, fmt.Sprintf("converts column %s to character set %s", name, table.ColumnCharset(to))
+	}
+	switch loss {
+	case DataSignChange:
+		return RiskDestructive, "changes the sign of column " + name + ", may truncate data"
+	case DataNarrowing:
+		return RiskDestructive, "narrows the type of column " + name + ", may truncate data"
+	}
+	if isNotNull(to) && !isNotNull(from) {
+		return RiskDestructive, "adds NOT NULL to column " + name
+	}
+
+	// the changes of the metadata don't rebuild the table.
+	if c.Position == nil {
+		var rebuild bool
+		for _, attr := range from.Compare(to) {
+			switch attr {
+			case model.ColumnAttributeName, model.ColumnAttributeDefault, model.ColumnAttributeComment:
+			default:
+				rebuild = true
+			}
+		}
+		if !rebuild {
+			return RiskSafe, ""
+		}
+	}
+	return RiskBlocking, "rebuilds the table to change column " + name
+}
+
+func isNotNull(col *model.TableColumn) bool {
+	return col.NullState == model.NullStateNotNull
+}
+
+// lengthOf returns the length of the column, or def if it is not specified.
+func lengthOf(col *model.TableColumn, def int) int {
+	if col.Length == nil {
+		return def
+	}
+	l, err := strconv.Atoi(col.Length.Length)
+	if err != nil {
+		return def
+	}
+	return l
+}
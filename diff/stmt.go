@@ -6,9 +6,18 @@ import (
 )
 
 // Stmt is an SQL statement.
-type Stmt string
+// Diff returns the statements as the operations defined in this package,
+// such as *CreateTable, *DropTable and *AlterTable,
+// so the consumers can inspect them with type switches.
+type Stmt interface {
+	// String returns the SQL text of the statement.
+	String() string
+}
+
+// RawStmt is an SQL statement that has no structure, such as BEGIN and COMMIT.
+type RawStmt string
 
-func (s Stmt) String() string {
+func (s RawStmt) String() string {
 	return string(s)
 }
 
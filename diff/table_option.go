@@ -0,0 +1,169 @@
+package diff
+
+import (
+	"strings"
+
+	"github.com/shogo82148/schemalex-deploy/model"
+)
+
+// DefaultIgnoredTableOptions is the list of the table options that are not compared by default.
+// AUTO_INCREMENT is changed by inserting rows, so it is not a part of the schema.
+var DefaultIgnoredTableOptions = []string{"AUTO_INCREMENT"}
+
+const (
+	tableOptionCharset   = "DEFAULT CHARACTER SET"
+	tableOptionCollation = "DEFAULT COLLATE"
+)
+
+// tableOptionDefaults is the values that MySQL uses if the table options are omitted.
+// The options that are not listed here can't be reset by ALTER TABLE.
+var tableOptionDefaults = map[string]*model.TableOption{
+	"AVG_ROW_LENGTH":     model.NewTableOption("AVG_ROW_LENGTH", "0", false),
+	"CHECKSUM":           model.NewTableOption("CHECKSUM", "0", false),
+	"COMMENT":            model.NewTableOption("COMMENT", "", true),
+	"CONNECTION":         model.NewTableOption("CONNECTION", "", true),
+	"ENGINE":             model.NewTableOption("ENGINE", "InnoDB", false),
+	"KEY_BLOCK_SIZE":     model.NewTableOption("KEY_BLOCK_SIZE", "0", false),
+	"MAX_ROWS":           model.NewTableOption("MAX_ROWS", "0", false),
+	"MIN_ROWS":           model.NewTableOption("MIN_ROWS", "0", false),
+	"PACK_KEYS":          model.NewTableOption("PACK_KEYS", "DEFAULT", false),
+	"ROW_FORMAT":         model.NewTableOption("ROW_FORMAT", "DEFAULT", false),
+	"STATS_AUTO_RECALC":  model.NewTableOption("STATS_AUTO_RECALC", "DEFAULT", false),
+	"STATS_PERSISTENT":   model.NewTableOption("STATS_PERSISTENT", "DEFAULT", false),
+	"STATS_SAMPLE_PAGES": model.NewTableOption("STATS_SAMPLE_PAGES", "DEFAULT", false),
+}
+
+// defaultCollations is the default collations of the character sets in MySQL 8.0.
+var defaultCollations = map[string]string{
+	"ascii":   "ascii_general_ci",
+	"binary":  "binary",
+	"latin1":  "latin1_swedish_ci",
+	"utf8":    "utf8mb3_general_ci",
+	"utf8mb3": "utf8mb3_general_ci",
+	"utf8mb4": "utf8mb4_0900_ai_ci",
+}
+
+// normalizeTableOptionKey converts the key of the table option into the form that the parser uses.
+func normalizeTableOptionKey(key string) string {
+	key = strings.ToUpper(strings.Join(strings.Fields(key), " "))
+	switch key {
+	case "CHARSET", "CHARACTER SET", "DEFAULT CHARSET":
+		return tableOptionCharset
+	case "COLLATE":
+		return tableOptionCollation
+	}
+	return key
+}
+
+// effectiveTableOption returns the value of the table option that MySQL uses.
+func effectiveTableOption(t *model.Table, key string) (*model.TableOption, bool) {
+	if opt, ok := t.LookupOption(key); ok {
+		return opt, true
+	}
+	opt, ok := tableOptionDefaults[key]
+	return opt, ok
+}
+
+// effectiveCollation returns the default collation of the table.
+// It returns an empty string if it is unknown.
+func effectiveCollation(t *model.Table) string {
+	if opt, ok := t.LookupOption(tableOptionCollation); ok {
+		return opt.Value
+	}
+	return defaultCollations[strings.ToLower(string(t.Charset()))]
+}
+
+func equalTableOptionValue(a, b *model.TableOption) bool {
+	if a.NeedQuotes || b.NeedQuotes {
+		return a.Value == b.Value
+	}
+	return strings.EqualFold(a.Value, b.Value)
+}
+
+func (ctx *alterCtx) alterTableOptions() error {
+	// collect the keys in the order of appearance.
+	var keys []string
+	seen := make(map[string]struct{})
+	for _, opts := range [][]*model.TableOption{ctx.to.Options, ctx.from.Options} {
+		for _, opt := range opts {
+			key := normalizeTableOptionKey(opt.Key)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			keys = append(keys, key)
+		}
+	}
+
+	charsetDone := false
+	for _, key := range keys {
+		if _, ok := ctx.ignoredTableOptions[key]; ok {
+			continue
+		}
+		if key == tableOptionCharset || key == tableOptionCollation {
+			if !charsetDone {
+				charsetDone = true
+				if err := ctx.alterTableCharset(); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		from, fromOK := effectiveTableOption(ctx.from, key)
+		to, toOK := effectiveTableOption(ctx.to, key)
+		if !toOK {
+			// we can't reset the option.
+			continue
+		}
+		if fromOK && equalTableOptionValue(from, to) {
+			continue
+		}
+		if err := ctx.writeTableOption(to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ctx *alterCtx) alterTableCharset() error {
+	if ctx.charsetConverted {
+		// CONVERT TO CHARACTER SET has changed the default.
+		return nil
+	}
+	_, ignoreCharset := ctx.ignoredTableOptions[tableOptionCharset]
+	_, ignoreCollation := ctx.ignoredTableOptions[tableOptionCollation]
+
+	fromCharset, toCharset := ctx.from.Charset(), ctx.to.Charset()
+	if !ignoreCharset && !strings.EqualFold(string(fromCharset), string(toCharset)) {
+		if err := ctx.writeTableOption(model.NewTableOption(tableOptionCharset, string(toCharset), false)); err != nil {
+			return err
+		}
+		// MySQL uses the default collation of the character set, if it is omitted.
+		if opt, ok := ctx.to.LookupOption(tableOptionCollation); ok && !ignoreCollation {
+			return ctx.writeTableOption(opt)
+		}
+		return nil
+	}
+
+	if ignoreCollation {
+		return nil
+	}
+	if opt, ok := ctx.to.LookupOption(tableOptionCollation); ok {
+		if !strings.EqualFold(effectiveCollation(ctx.from), opt.Value) {
+			return ctx.writeTableOption(opt)
+		}
+		return nil
+	}
+	if opt, ok := ctx.from.LookupOption(tableOptionCollation); ok && !strings.EqualFold(effectiveCollation(ctx.to), opt.Value) {
+		// specifying the character set resets the collation to the default of the server,
+		// that may be different from the one we know.
+		return ctx.writeTableOption(model.NewTableOption(tableOptionCharset, string(toCharset), false))
+	}
+	return nil
+}
+
+func (ctx *alterCtx) writeTableOption(opt *model.TableOption) error {
+	ctx.add(&SetTableOption{Option: opt})
+	return nil
+}
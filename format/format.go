@@ -402,6 +402,8 @@ func formatIndex(ctx *fmtCtx, index *model.Index) error {
 
 	if ref := index.Reference; ref != nil {
 		newctx := ctx.clone()
+		// the reference follows the index on the same line, so it is not indented.
+		newctx.curIndent = ""
 		newctx.dst = &buf
 
 		buf.WriteByte(' ')
@@ -537,3 +537,27 @@ primary key (id, c)
 			") ENGINE = InnoDB, DEFAULT CHARACTER SET = utf8mb4;\n",
 	})
 }
+
+func TestFormat_IndentedReference(t *testing.T) {
+	p := schemalex.New()
+	stmts, err := p.ParseString("CREATE TABLE `a` ( `id` INTEGER NOT NULL, `bid` INTEGER NOT NULL, CONSTRAINT `a_fk` FOREIGN KEY (`bid`) REFERENCES `b` (`id`) )")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf strings.Builder
+	if err := SQL(&buf, stmts, WithIndent("  ", 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the indent is written only at the beginning of the lines, not before REFERENCES.
+	want := "CREATE TABLE `a` (\n" +
+		"  `id` INT (11) NOT NULL,\n" +
+		"  `bid` INT (11) NOT NULL,\n" +
+		"  INDEX `a_fk` (`bid`),\n" +
+		"  CONSTRAINT `a_fk` FOREIGN KEY (`bid`) REFERENCES `b` (`id`)\n" +
+		");\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("(-want/+got):\n%s", diff)
+	}
+}
@@ -0,0 +1,257 @@
+package model
+
+// TableBuilder builds a Table from Go code.
+// The result of Build is normalized in the same way as the parser does,
+// so it can be passed to diff.Diff and format.SQL as-is.
+//
+//	table := model.NewTableBuilder("audit_log").
+//		Column(model.NewColumnBuilder("id", model.ColumnTypeBigInt).Unsigned().NotNull().AutoIncrement()).
+//		Column(model.NewColumnBuilder("body", model.ColumnTypeText).NotNull()).
+//		PrimaryKey("id").
+//		Option("ENGINE", "InnoDB", false).
+//		Build()
+type TableBuilder struct {
+	table *Table
+}
+
+// NewTableBuilder creates a new builder of the table with the given name.
+func NewTableBuilder(name Ident) *TableBuilder {
+	return &TableBuilder{
+		table: NewTable(name),
+	}
+}
+
+// Temporary marks the table as a temporary table.
+func (b *TableBuilder) Temporary() *TableBuilder {
+	b.table.Temporary = true
+	return b
+}
+
+// IfNotExists adds IF NOT EXISTS to the table.
+func (b *TableBuilder) IfNotExists() *TableBuilder {
+	b.table.IfNotExists = true
+	return b
+}
+
+// Column appends a column to the table.
+func (b *TableBuilder) Column(col *ColumnBuilder) *TableBuilder {
+	b.table.Columns = append(b.table.Columns, col.Build())
+	return b
+}
+
+// PrimaryKey adds PRIMARY KEY on the given columns.
+func (b *TableBuilder) PrimaryKey(columns ...Ident) *TableBuilder {
+	return b.addIndex(IndexKindPrimaryKey, MaybeIdent{}, columns)
+}
+
+// Index adds INDEX with the given name on the given columns.
+func (b *TableBuilder) Index(name Ident, columns ...Ident) *TableBuilder {
+	return b.addIndex(IndexKindNormal, MaybeIdent{Ident: name, Valid: true}, columns)
+}
+
+// UniqueIndex adds UNIQUE INDEX with the given name on the given columns.
+func (b *TableBuilder) UniqueIndex(name Ident, columns ...Ident) *TableBuilder {
+	return b.addIndex(IndexKindUnique, MaybeIdent{Ident: name, Valid: true}, columns)
+}
+
+// FullTextIndex adds FULLTEXT INDEX with the given name on the given columns.
+func (b *TableBuilder) FullTextIndex(name Ident, columns ...Ident) *TableBuilder {
+	return b.addIndex(IndexKindFullText, MaybeIdent{Ident: name, Valid: true}, columns)
+}
+
+func (b *TableBuilder) addIndex(kind IndexKind, name MaybeIdent, columns []Ident) *TableBuilder {
+	idx := NewIndex(kind, b.table.ID())
+	idx.Name = name
+	for _, col := range columns {
+		idx.Columns = append(idx.Columns, NewIndexColumn(col))
+	}
+	b.table.Indexes = append(b.table.Indexes, idx)
+	return b
+}
+
+// ForeignKey adds CONSTRAINT `symbol` FOREIGN KEY (columns) REFERENCES with the given reference.
+// The implicit index for the constraint is created by Build.
+func (b *TableBuilder) ForeignKey(symbol Ident, columns []Ident, ref *Reference) *TableBuilder {
+	idx := NewIndex(IndexKindForeignKey, b.table.ID())
+	idx.ConstraintName = MaybeIdent{Ident: symbol, Valid: true}
+	for _, col := range columns {
+		idx.Columns = append(idx.Columns, NewIndexColumn(col))
+	}
+	idx.Reference = ref
+	b.table.Indexes = append(b.table.Indexes, idx)
+	return b
+}
+
+// RawIndex appends an index to the table as-is.
+// It is useful for the indexes that the other methods don't support.
+func (b *TableBuilder) RawIndex(idx *Index) *TableBuilder {
+	idx = idx.Clone()
+	idx.Table = b.table.ID()
+	b.table.Indexes = append(b.table.Indexes, idx)
+	return b
+}
+
+// Option adds a table option, such as `ENGINE=InnoDB`.
+func (b *TableBuilder) Option(key, value string, quotes bool) *TableBuilder {
+	b.table.Options = append(b.table.Options, NewTableOption(key, value, quotes))
+	return b
+}
+
+// Comment adds the COMMENT table option.
+func (b *TableBuilder) Comment(comment string) *TableBuilder {
+	return b.Option("COMMENT", comment, true)
+}
+
+// RenamedFrom records the old name of the table, so that diff renames the table.
+func (b *TableBuilder) RenamedFrom(name Ident) *TableBuilder {
+	b.table.RenamedFrom = MaybeIdent{Ident: name, Valid: true}
+	return b
+}
+
+// Build returns the normalized table.
+// The builder can be reused, and the returned table doesn't share any memory with the builder.
+func (b *TableBuilder) Build() *Table {
+	return b.table.Clone().Normalize()
+}
+
+// NewReferenceTo creates a reference constraint to the columns of the table.
+func NewReferenceTo(table Ident, columns ...Ident) *Reference {
+	ref := NewReference()
+	ref.TableName = table
+	for _, col := range columns {
+		ref.Columns = append(ref.Columns, NewIndexColumn(col))
+	}
+	return ref
+}
+
+// ColumnBuilder builds a TableColumn from Go code.
+type ColumnBuilder struct {
+	col *TableColumn
+}
+
+// NewColumnBuilder creates a new builder of the column with the given name and type.
+func NewColumnBuilder(name Ident, typ ColumnType) *ColumnBuilder {
+	col := NewTableColumn(string(name))
+	col.Type = typ
+	return &ColumnBuilder{
+		col: col,
+	}
+}
+
+// Length sets the length of the column, such as VARCHAR(255).
+func (b *ColumnBuilder) Length(length string) *ColumnBuilder {
+	b.col.Length = NewLength(length)
+	return b
+}
+
+// Decimals sets the length and the decimals of the column, such as DECIMAL(10,2).
+func (b *ColumnBuilder) Decimals(length, decimals string) *ColumnBuilder {
+	b.col.Length = &Length{
+		Length:   length,
+		Decimals: MaybeString{Valid: true, Value: decimals},
+	}
+	return b
+}
+
+// Unsigned marks the column as UNSIGNED.
+func (b *ColumnBuilder) Unsigned() *ColumnBuilder {
+	b.col.Unsigned = true
+	return b
+}
+
+// ZeroFill marks the column as ZEROFILL.
+func (b *ColumnBuilder) ZeroFill() *ColumnBuilder {
+	b.col.ZeroFill = true
+	return b
+}
+
+// Binary marks the column as BINARY.
+func (b *ColumnBuilder) Binary() *ColumnBuilder {
+	b.col.Binary = true
+	return b
+}
+
+// NotNull marks the column as NOT NULL.
+func (b *ColumnBuilder) NotNull() *ColumnBuilder {
+	b.col.NullState = NullStateNotNull
+	return b
+}
+
+// Null marks the column as NULL.
+func (b *ColumnBuilder) Null() *ColumnBuilder {
+	b.col.NullState = NullStateNull
+	return b
+}
+
+// CharacterSet sets the character set of the column.
+func (b *ColumnBuilder) CharacterSet(charset Ident) *ColumnBuilder {
+	b.col.CharacterSet = MaybeIdent{Ident: charset, Valid: true}
+	return b
+}
+
+// Collation sets the collation of the column.
+func (b *ColumnBuilder) Collation(collation Ident) *ColumnBuilder {
+	b.col.Collation = MaybeIdent{Ident: collation, Valid: true}
+	return b
+}
+
+// Default sets the default value of the column. The value is quoted as a string literal.
+func (b *ColumnBuilder) Default(value string) *ColumnBuilder {
+	b.col.Default = DefaultValue{Valid: true, Value: value, Quoted: true}
+	return b
+}
+
+// DefaultExpr sets the default value of the column. The value is used as-is,
+// e.g. NULL, 0 and CURRENT_TIMESTAMP.
+func (b *ColumnBuilder) DefaultExpr(value string) *ColumnBuilder {
+	b.col.Default = DefaultValue{Valid: true, Value: value, Quoted: false}
+	return b
+}
+
+// OnUpdate sets ON UPDATE clause of the column, such as ON UPDATE CURRENT_TIMESTAMP.
+func (b *ColumnBuilder) OnUpdate(value string) *ColumnBuilder {
+	b.col.AutoUpdate = MaybeString{Valid: true, Value: value}
+	return b
+}
+
+// AutoIncrement marks the column as AUTO_INCREMENT.
+func (b *ColumnBuilder) AutoIncrement() *ColumnBuilder {
+	b.col.AutoIncrement = true
+	return b
+}
+
+// Comment sets the comment of the column.
+func (b *ColumnBuilder) Comment(comment string) *ColumnBuilder {
+	b.col.Comment = MaybeString{Valid: true, Value: comment}
+	return b
+}
+
+// EnumValues sets the values of the ENUM column.
+func (b *ColumnBuilder) EnumValues(values ...string) *ColumnBuilder {
+	b.col.EnumValues = values
+	return b
+}
+
+// SetValues sets the values of the SET column.
+func (b *ColumnBuilder) SetValues(values ...string) *ColumnBuilder {
+	b.col.SetValues = values
+	return b
+}
+
+// SRID sets the spatial reference system identifier of the column.
+func (b *ColumnBuilder) SRID(srid int64) *ColumnBuilder {
+	b.col.SRID = MaybeInteger{Valid: true, Value: srid}
+	return b
+}
+
+// RenamedFrom records the old name of the column, so that diff renames the column.
+func (b *ColumnBuilder) RenamedFrom(name Ident) *ColumnBuilder {
+	b.col.RenamedFrom = MaybeIdent{Ident: name, Valid: true}
+	return b
+}
+
+// Build returns a copy of the column.
+// The column is not normalized. It is normalized when it is added to the table.
+func (b *ColumnBuilder) Build() *TableColumn {
+	return b.col.Clone()
+}
@@ -0,0 +1,103 @@
+package model_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/schemalex-deploy"
+	"github.com/shogo82148/schemalex-deploy/diff"
+	"github.com/shogo82148/schemalex-deploy/format"
+	"github.com/shogo82148/schemalex-deploy/model"
+)
+
+func ExampleTableBuilder() {
+	table := model.NewTableBuilder("audit_log").
+		Column(model.NewColumnBuilder("id", model.ColumnTypeBigInt).Unsigned().NotNull().AutoIncrement()).
+		Column(model.NewColumnBuilder("user_id", model.ColumnTypeInt).NotNull()).
+		Column(model.NewColumnBuilder("body", model.ColumnTypeText).NotNull()).
+		Column(model.NewColumnBuilder("created_at", model.ColumnTypeDateTime).NotNull().DefaultExpr("CURRENT_TIMESTAMP")).
+		PrimaryKey("id").
+		Index("idx_created_at", "created_at").
+		ForeignKey("fk_user", []model.Ident{"user_id"}, model.NewReferenceTo("users", "id")).
+		Option("ENGINE", "InnoDB", false).
+		Build()
+
+	format.SQL(os.Stdout, table, format.WithIndent(" ", 2))
+
+	// OUTPUT:
+	// CREATE TABLE `audit_log` (
+	//   `id` BIGINT (20) UNSIGNED NOT NULL AUTO_INCREMENT,
+	//   `user_id` INT (11) NOT NULL,
+	//   `body` TEXT NOT NULL,
+	//   `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	//   PRIMARY KEY (`id`),
+	//   INDEX `idx_created_at` (`created_at`),
+	//   INDEX `fk_user` (`user_id`),
+	//   CONSTRAINT `fk_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)
+	// ) ENGINE = InnoDB
+}
+
+func TestTableBuilder(t *testing.T) {
+	const sql = "CREATE TABLE `hoge` (" +
+		"`id` INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY, " +
+		"`name` VARCHAR (255) NOT NULL DEFAULT 'anonymous' COMMENT 'user name', " +
+		"`price` DECIMAL (10,2), " +
+		"UNIQUE INDEX `uniq_name` (`name`)" +
+		") ENGINE = InnoDB, COMMENT = 'hoge table'"
+	stmts, err := schemalex.New().ParseString(sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table := model.NewTableBuilder("hoge").
+		Column(model.NewColumnBuilder("id", model.ColumnTypeInteger).NotNull().AutoIncrement()).
+		Column(model.NewColumnBuilder("name", model.ColumnTypeVarChar).Length("255").NotNull().Default("anonymous").Comment("user name")).
+		Column(model.NewColumnBuilder("price", model.ColumnTypeDecimal).Decimals("10", "2")).
+		PrimaryKey("id").
+		UniqueIndex("uniq_name", "name").
+		Option("ENGINE", "InnoDB", false).
+		Comment("hoge table").
+		Build()
+
+	stmts2, err := diff.Diff(stmts, model.Stmts{table})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts2) != 0 {
+		t.Errorf("want no difference, got %v", stmts2)
+	}
+}
+
+func TestTableClone(t *testing.T) {
+	stmts, err := schemalex.New().ParseString("CREATE TABLE `hoge` (" +
+		"`id` INTEGER NOT NULL, `kind` ENUM('a', 'b') NOT NULL, " +
+		"`fid` INTEGER NOT NULL, " +
+		"CONSTRAINT `fk` FOREIGN KEY (`fid`) REFERENCES `fuga` (`id`))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := stmts[0].(*model.Table)
+	clone := orig.Clone()
+	if diff := cmp.Diff(orig, clone); diff != "" {
+		t.Errorf("clone mismatch (-want/+got):\n%s", diff)
+	}
+
+	// modifying the clone must not affect the original.
+	clone.Columns[0].Length.Length = "20"
+	clone.Columns[1].EnumValues[0] = "c"
+	clone.Indexes[1].Columns[0].Name = "xxx"
+	clone.Indexes[1].Reference.Columns[0].Name = "yyy"
+	if orig.Columns[0].Length.Length != "11" {
+		t.Errorf("the length of the original is changed: %s", orig.Columns[0].Length.Length)
+	}
+	if orig.Columns[1].EnumValues[0] != "a" {
+		t.Errorf("the enum values of the original are changed: %v", orig.Columns[1].EnumValues)
+	}
+	if orig.Indexes[1].Columns[0].Name != "fid" {
+		t.Errorf("the index column of the original is changed: %s", orig.Indexes[1].Columns[0].Name)
+	}
+	if orig.Indexes[1].Reference.Columns[0].Name != "id" {
+		t.Errorf("the reference of the original is changed: %s", orig.Indexes[1].Reference.Columns[0].Name)
+	}
+}
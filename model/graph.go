@@ -0,0 +1,134 @@
+package model
+
+import "strings"
+
+// ForeignKeys returns the foreign keys of the table.
+func (t *Table) ForeignKeys() []*Index {
+	var ret []*Index
+	for _, idx := range t.Indexes {
+		if idx.Kind == IndexKindForeignKey && idx.Reference != nil {
+			ret = append(ret, idx)
+		}
+	}
+	return ret
+}
+
+// ReferencedTables returns the IDs of the tables that t refers by its foreign keys.
+// The result doesn't contain duplicates, and it doesn't contain t itself.
+func (t *Table) ReferencedTables() []string {
+	var ret []string
+	id := t.ID()
+	seen := map[string]struct{}{id: {}}
+	for _, fk := range t.ForeignKeys() {
+		ref := fk.Reference.TableID()
+		if _, ok := seen[ref]; ok {
+			continue
+		}
+		seen[ref] = struct{}{}
+		ret = append(ret, ref)
+	}
+	return ret
+}
+
+// TableID returns the ID of the table that r refers.
+func (r *Reference) TableID() string {
+	return "table#" + strings.ToLower(string(r.TableName))
+}
+
+// CycleError is returned by SortTables if the foreign keys between the tables make a cycle.
+type CycleError struct {
+	// Tables is the names of the tables that make the cycle.
+	// The first table refers the second one, the second one refers the third one,
+	// and the last one refers the first one.
+	Tables []Ident
+}
+
+func (e *CycleError) Error() string {
+	var buf strings.Builder
+	buf.WriteString("model: circular foreign key references: ")
+	for _, name := range e.Tables {
+		buf.WriteString(name.Quoted())
+		buf.WriteString(" -> ")
+	}
+	if len(e.Tables) > 0 {
+		buf.WriteString(e.Tables[0].Quoted())
+	}
+	return buf.String()
+}
+
+// SortTables sorts the tables so that every table comes after the tables that it refers by foreign keys.
+// References to the tables that are not in the list are ignored.
+// The order of the input is kept as far as possible.
+//
+// If the foreign keys make a cycle, SortTables returns a *CycleError
+// together with the tables sorted as far as possible.
+func SortTables(tables []*Table) ([]*Table, error) {
+	byID := make(map[string]*Table, len(tables))
+	for _, t := range tables {
+		byID[t.ID()] = t
+	}
+
+	sorted := make([]*Table, 0, len(tables))
+	done := make(map[string]bool, len(tables))
+	for len(sorted) < len(tables) {
+		progress := false
+		for _, t := range tables {
+			if done[t.ID()] || !dependenciesDone(t, byID, done) {
+				continue
+			}
+			done[t.ID()] = true
+			sorted = append(sorted, t)
+			progress = true
+		}
+		if !progress {
+			break
+		}
+	}
+	if len(sorted) == len(tables) {
+		return sorted, nil
+	}
+
+	// the remaining tables are in a cycle or depend on a cycle.
+	var rest []*Table
+	for _, t := range tables {
+		if !done[t.ID()] {
+			rest = append(rest, t)
+		}
+	}
+	sorted = append(sorted, rest...)
+	return sorted, &CycleError{Tables: findCycle(rest[0], byID, done)}
+}
+
+func dependenciesDone(t *Table, byID map[string]*Table, done map[string]bool) bool {
+	for _, ref := range t.ReferencedTables() {
+		if _, ok := byID[ref]; ok && !done[ref] {
+			return false
+		}
+	}
+	return true
+}
+
+// findCycle follows the references from t until it finds a cycle.
+func findCycle(t *Table, byID map[string]*Table, done map[string]bool) []Ident {
+	var path []*Table
+	visited := map[string]int{}
+	for {
+		if i, ok := visited[t.ID()]; ok {
+			var names []Ident
+			for _, t := range path[i:] {
+				names = append(names, t.Name)
+			}
+			return names
+		}
+		visited[t.ID()] = len(path)
+		path = append(path, t)
+
+		// t is not done, so at least one of the referenced tables is not done.
+		for _, ref := range t.ReferencedTables() {
+			if next, ok := byID[ref]; ok && !done[ref] {
+				t = next
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package model_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/schemalex-deploy"
+	"github.com/shogo82148/schemalex-deploy/model"
+)
+
+func parseTables(t *testing.T, sql string) []*model.Table {
+	t.Helper()
+	stmts, err := schemalex.New().ParseString(sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tables []*model.Table
+	for _, stmt := range stmts {
+		tables = append(tables, stmt.(*model.Table))
+	}
+	return tables
+}
+
+func tableNames(tables []*model.Table) []model.Ident {
+	var names []model.Ident
+	for _, t := range tables {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+func TestSortTables(t *testing.T) {
+	tables := parseTables(t, "CREATE TABLE `a` ( `id` INTEGER, `cid` INTEGER, FOREIGN KEY (`cid`) REFERENCES `c` (`id`) );\n"+
+		"CREATE TABLE `b` ( `id` INTEGER, `bid` INTEGER, FOREIGN KEY (`bid`) REFERENCES `b` (`id`) );\n"+
+		"CREATE TABLE `c` ( `id` INTEGER, `did` INTEGER, FOREIGN KEY (`did`) REFERENCES `d` (`id`) );\n"+
+		"CREATE TABLE `d` ( `id` INTEGER, `xid` INTEGER, FOREIGN KEY (`xid`) REFERENCES `x` (`id`) );\n")
+
+	sorted, err := model.SortTables(tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.Ident{"b", "d", "c", "a"}
+	if diff := cmp.Diff(want, tableNames(sorted)); diff != "" {
+		t.Errorf("mismatch (-want/+got):\n%s", diff)
+	}
+}
+
+func TestSortTables_Cycle(t *testing.T) {
+	tables := parseTables(t, "CREATE TABLE `a` ( `id` INTEGER, `bid` INTEGER, FOREIGN KEY (`bid`) REFERENCES `b` (`id`) );\n"+
+		"CREATE TABLE `b` ( `id` INTEGER, `cid` INTEGER, FOREIGN KEY (`cid`) REFERENCES `c` (`id`) );\n"+
+		"CREATE TABLE `c` ( `id` INTEGER, `bid` INTEGER, FOREIGN KEY (`bid`) REFERENCES `b` (`id`) );\n"+
+		"CREATE TABLE `d` ( `id` INTEGER );\n")
+
+	sorted, err := model.SortTables(tables)
+	var cycle *model.CycleError
+	if !errors.As(err, &cycle) {
+		t.Fatalf("want CycleError, got %v", err)
+	}
+	if diff := cmp.Diff([]model.Ident{"b", "c"}, cycle.Tables); diff != "" {
+		t.Errorf("cycle mismatch (-want/+got):\n%s", diff)
+	}
+	if want, got := "model: circular foreign key references: `b` -> `c` -> `b`", err.Error(); want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if diff := cmp.Diff([]model.Ident{"d", "a", "b", "c"}, tableNames(sorted)); diff != "" {
+		t.Errorf("mismatch (-want/+got):\n%s", diff)
+	}
+}
@@ -40,3 +40,12 @@ type MaybeInteger struct {
 	Valid bool
 	Value int64
 }
+
+// equalMaybeIdentFold reports whether a and b are same, ignoring the case of the identifiers.
+// It is useful for the names such as character sets and collations.
+func equalMaybeIdentFold(a, b MaybeIdent) bool {
+	if a.Valid != b.Valid {
+		return false
+	}
+	return !a.Valid || strings.EqualFold(string(a.Ident), string(b.Ident))
+}
@@ -6,6 +6,7 @@ package model
 import (
 	"crypto/sha256"
 	"fmt"
+	"slices"
 	"strings"
 )
 
@@ -51,6 +52,10 @@ type Index struct {
 	Columns        []*IndexColumn
 	Reference      *Reference
 	Options        []*IndexOption
+
+	// Pos is the position of the index in the source.
+	// It is set only if the parser records positions.
+	Pos Position
 }
 
 // NewIndex creates a new index with the given index kind.
@@ -97,8 +102,48 @@ func (idx *Index) ID() string {
 	return fmt.Sprintf("%s#%x", name, h.Sum(nil))
 }
 
+// Equal returns whether idx and other have same definition including their names.
+// The table that the index belongs to is not compared.
+func (idx *Index) Equal(other *Index) bool {
+	if idx.Kind != other.Kind || idx.Type != other.Type {
+		return false
+	}
+	if !equalMaybeIdentFold(idx.Name, other.Name) {
+		return false
+	}
+	if !equalMaybeIdentFold(idx.ConstraintName, other.ConstraintName) {
+		return false
+	}
+	if !slices.EqualFunc(idx.Columns, other.Columns, (*IndexColumn).Equal) {
+		return false
+	}
+	if (idx.Reference != nil) != (other.Reference != nil) {
+		return false
+	}
+	if idx.Reference != nil && !idx.Reference.Equal(other.Reference) {
+		return false
+	}
+	return slices.EqualFunc(idx.Options, other.Options, (*IndexOption).Equal)
+}
+
 func (stmt *Index) Normalize() *Index {
+	return stmt.Clone()
+}
+
+// Clone returns a deep copy of the index.
+func (stmt *Index) Clone() *Index {
 	newindex := *stmt
+	newindex.Columns = cloneIndexColumns(stmt.Columns)
+	if stmt.Reference != nil {
+		newindex.Reference = stmt.Reference.Clone()
+	}
+	if stmt.Options != nil {
+		newindex.Options = make([]*IndexOption, len(stmt.Options))
+		for i, opt := range stmt.Options {
+			o := *opt
+			newindex.Options[i] = &o
+		}
+	}
 	return &newindex
 }
 
@@ -115,6 +160,25 @@ func NewIndexColumn(name Ident) *IndexColumn {
 	}
 }
 
+func cloneIndexColumns(cols []*IndexColumn) []*IndexColumn {
+	if cols == nil {
+		return nil
+	}
+	ret := make([]*IndexColumn, len(cols))
+	for i, col := range cols {
+		c := *col
+		ret[i] = &c
+	}
+	return ret
+}
+
+// Equal returns whether col and other refer the same column in the same way.
+func (col *IndexColumn) Equal(other *IndexColumn) bool {
+	return strings.EqualFold(string(col.Name), string(other.Name)) &&
+		col.Length == other.Length &&
+		col.SortDirection == other.SortDirection
+}
+
 func (col *IndexColumn) ID() string {
 	name := strings.ToLower(string(col.Name))
 	if col.Length.Valid {
@@ -139,3 +203,8 @@ func NewIndexOption(k, v string, q bool) *IndexOption {
 }
 
 func (opt *IndexOption) ID() string { return "indexopt#" + strings.ToLower(opt.Key) }
+
+// Equal returns whether opt and other have same key and value.
+func (opt *IndexOption) Equal(other *IndexOption) bool {
+	return strings.EqualFold(opt.Key, other.Key) && opt.Value == other.Value
+}
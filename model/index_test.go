@@ -0,0 +1,41 @@
+package model
+
+import "testing"
+
+func TestIndexEqual(t *testing.T) {
+	newIndex := func(table string, name Ident, cols ...Ident) *Index {
+		idx := NewIndex(IndexKindNormal, table)
+		idx.Name = MaybeIdent{Ident: name, Valid: true}
+		for _, col := range cols {
+			idx.Columns = append(idx.Columns, NewIndexColumn(col))
+		}
+		return idx
+	}
+
+	if !newIndex("table#foo", "idx", "a", "b").Equal(newIndex("table#bar", "IDX", "A", "b")) {
+		t.Error("want equal, but not")
+	}
+	if newIndex("table#foo", "idx", "a", "b").Equal(newIndex("table#foo", "idx", "b", "a")) {
+		t.Error("want not equal, but equal")
+	}
+	if newIndex("table#foo", "idx", "a").Equal(newIndex("table#foo", "idx2", "a")) {
+		t.Error("want not equal, but equal")
+	}
+
+	fk1 := newIndex("table#foo", "fk", "a")
+	fk1.Kind = IndexKindForeignKey
+	fk1.Reference = &Reference{TableName: "bar", Columns: []*IndexColumn{NewIndexColumn("id")}}
+	fk2 := newIndex("table#foo", "fk", "a")
+	fk2.Kind = IndexKindForeignKey
+	fk2.Reference = &Reference{TableName: "bar", Columns: []*IndexColumn{NewIndexColumn("id")}, OnDelete: ReferenceOptionCascade}
+	if fk1.Equal(fk2) {
+		t.Error("want not equal, but equal")
+	}
+
+	fk3 := newIndex("table#foo", "fk", "a")
+	fk3.Kind = IndexKindForeignKey
+	fk3.Reference = &Reference{TableName: "BAR", Columns: []*IndexColumn{NewIndexColumn("id")}}
+	if !fk1.Equal(fk3) {
+		t.Error("want equal, but not equal")
+	}
+}
@@ -0,0 +1,581 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// This file defines the JSON representation of the schema model.
+// The representation is stable: enums are written as their SQL keywords,
+// and optional values are omitted if they are not set.
+
+// MarshalText implements encoding.TextMarshaler.
+func (c ColumnType) MarshalText() ([]byte, error) {
+	if c <= ColumnTypeInvalid || c >= ColumnTypeMax {
+		return nil, fmt.Errorf("model: invalid column type: %d", int(c))
+	}
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *ColumnType) UnmarshalText(text []byte) error {
+	for typ := ColumnTypeInvalid + 1; typ < ColumnTypeMax; typ++ {
+		if typ.String() == string(text) {
+			*c = typ
+			return nil
+		}
+	}
+	return fmt.Errorf("model: unknown column type: %q", text)
+}
+
+var nullStateNames = []string{
+	NullStateNone:    "",
+	NullStateNull:    "NULL",
+	NullStateNotNull: "NOT NULL",
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (n NullState) MarshalText() ([]byte, error) {
+	return marshalEnum("null state", nullStateNames, int(n))
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (n *NullState) UnmarshalText(text []byte) error {
+	return unmarshalEnum("null state", nullStateNames, (*int)(n), text)
+}
+
+var indexKindNames = []string{
+	IndexKindInvalid:    "",
+	IndexKindPrimaryKey: "PRIMARY KEY",
+	IndexKindNormal:     "INDEX",
+	IndexKindUnique:     "UNIQUE INDEX",
+	IndexKindFullText:   "FULLTEXT INDEX",
+	IndexKindSpatial:    "SPATIAL INDEX",
+	IndexKindForeignKey: "FOREIGN KEY",
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (k IndexKind) MarshalText() ([]byte, error) {
+	if k == IndexKindInvalid {
+		return nil, fmt.Errorf("model: invalid index kind: %d", int(k))
+	}
+	return marshalEnum("index kind", indexKindNames, int(k))
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (k *IndexKind) UnmarshalText(text []byte) error {
+	return unmarshalEnum("index kind", indexKindNames, (*int)(k), text)
+}
+
+var indexTypeNames = []string{
+	IndexTypeNone:  "",
+	IndexTypeBtree: "BTREE",
+	IndexTypeHash:  "HASH",
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t IndexType) MarshalText() ([]byte, error) {
+	return marshalEnum("index type", indexTypeNames, int(t))
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *IndexType) UnmarshalText(text []byte) error {
+	return unmarshalEnum("index type", indexTypeNames, (*int)(t), text)
+}
+
+var sortDirectionNames = []string{
+	SortDirectionNone:       "",
+	SortDirectionAscending:  "ASC",
+	SortDirectionDescending: "DESC",
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d IndexColumnSortDirection) MarshalText() ([]byte, error) {
+	return marshalEnum("sort direction", sortDirectionNames, int(d))
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *IndexColumnSortDirection) UnmarshalText(text []byte) error {
+	return unmarshalEnum("sort direction", sortDirectionNames, (*int)(d), text)
+}
+
+var referenceMatchNames = []string{
+	ReferenceMatchNone:    "",
+	ReferenceMatchFull:    "FULL",
+	ReferenceMatchPartial: "PARTIAL",
+	ReferenceMatchSimple:  "SIMPLE",
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (m ReferenceMatch) MarshalText() ([]byte, error) {
+	return marshalEnum("reference match", referenceMatchNames, int(m))
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (m *ReferenceMatch) UnmarshalText(text []byte) error {
+	return unmarshalEnum("reference match", referenceMatchNames, (*int)(m), text)
+}
+
+var referenceOptionNames = []string{
+	ReferenceOptionNone:     "",
+	ReferenceOptionRestrict: "RESTRICT",
+	ReferenceOptionCascade:  "CASCADE",
+	ReferenceOptionSetNull:  "SET NULL",
+	ReferenceOptionNoAction: "NO ACTION",
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (o ReferenceOption) MarshalText() ([]byte, error) {
+	return marshalEnum("reference option", referenceOptionNames, int(o))
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (o *ReferenceOption) UnmarshalText(text []byte) error {
+	return unmarshalEnum("reference option", referenceOptionNames, (*int)(o), text)
+}
+
+func marshalEnum(name string, names []string, v int) ([]byte, error) {
+	if v < 0 || v >= len(names) {
+		return nil, fmt.Errorf("model: invalid %s: %d", name, v)
+	}
+	return []byte(names[v]), nil
+}
+
+func unmarshalEnum(name string, names []string, v *int, text []byte) error {
+	for i, s := range names {
+		if s == string(text) {
+			*v = i
+			return nil
+		}
+	}
+	return fmt.Errorf("model: unknown %s: %q", name, text)
+}
+
+// maybeIdentPtr converts MaybeIdent into a pointer that is nil if it is not set.
+func maybeIdentPtr(v MaybeIdent) *Ident {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Ident
+}
+
+func maybeIdentFromPtr(v *Ident) MaybeIdent {
+	if v == nil {
+		return MaybeIdent{}
+	}
+	return MaybeIdent{Ident: *v, Valid: true}
+}
+
+func maybeStringPtr(v MaybeString) *string {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Value
+}
+
+func maybeStringFromPtr(v *string) MaybeString {
+	if v == nil {
+		return MaybeString{}
+	}
+	return MaybeString{Value: *v, Valid: true}
+}
+
+type jsonStmt struct {
+	Type string `json:"type"`
+}
+
+const (
+	jsonStmtTypeTable    = "table"
+	jsonStmtTypeDatabase = "database"
+)
+
+// MarshalJSON implements json.Marshaler.
+func (s Stmts) MarshalJSON() ([]byte, error) {
+	if s == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]Stmt(s))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *Stmts) UnmarshalJSON(data []byte) error {
+	var raws []json.RawMessage
+	if err := json.Unmarshal(data, &raws); err != nil {
+		return err
+	}
+
+	stmts := make(Stmts, 0, len(raws))
+	for _, raw := range raws {
+		var typ jsonStmt
+		if err := json.Unmarshal(raw, &typ); err != nil {
+			return err
+		}
+		switch typ.Type {
+		case jsonStmtTypeTable:
+			var table Table
+			if err := json.Unmarshal(raw, &table); err != nil {
+				return err
+			}
+			stmts = append(stmts, &table)
+		case jsonStmtTypeDatabase:
+			var database Database
+			if err := json.Unmarshal(raw, &database); err != nil {
+				return err
+			}
+			stmts = append(stmts, &database)
+		default:
+			return fmt.Errorf("model: unknown statement type: %q", typ.Type)
+		}
+	}
+	*s = stmts
+	return nil
+}
+
+type jsonDatabase struct {
+	Type        string `json:"type"`
+	Name        Ident  `json:"name"`
+	IfNotExists bool   `json:"if_not_exists,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d *Database) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonDatabase{
+		Type:        jsonStmtTypeDatabase,
+		Name:        d.Name,
+		IfNotExists: d.IfNotExists,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Database) UnmarshalJSON(data []byte) error {
+	var v jsonDatabase
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	d.Name = v.Name
+	d.IfNotExists = v.IfNotExists
+	return nil
+}
+
+type jsonTable struct {
+	Type        string         `json:"type"`
+	Name        Ident          `json:"name"`
+	Temporary   bool           `json:"temporary,omitempty"`
+	IfNotExists bool           `json:"if_not_exists,omitempty"`
+	LikeTable   *Ident         `json:"like_table,omitempty"`
+	Columns     []*TableColumn `json:"columns,omitempty"`
+	Indexes     []*Index       `json:"indexes,omitempty"`
+	Options     []*TableOption `json:"options,omitempty"`
+	RenamedFrom *Ident         `json:"renamed_from,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t *Table) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonTable{
+		Type:        jsonStmtTypeTable,
+		Name:        t.Name,
+		Temporary:   t.Temporary,
+		IfNotExists: t.IfNotExists,
+		LikeTable:   maybeIdentPtr(t.LikeTable),
+		Columns:     t.Columns,
+		Indexes:     t.Indexes,
+		Options:     t.Options,
+		RenamedFrom: maybeIdentPtr(t.RenamedFrom),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *Table) UnmarshalJSON(data []byte) error {
+	var v jsonTable
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*t = Table{
+		Name:        v.Name,
+		Temporary:   v.Temporary,
+		IfNotExists: v.IfNotExists,
+		LikeTable:   maybeIdentFromPtr(v.LikeTable),
+		Columns:     v.Columns,
+		Indexes:     v.Indexes,
+		Options:     v.Options,
+		RenamedFrom: maybeIdentFromPtr(v.RenamedFrom),
+	}
+	if t.Options == nil {
+		// the parser always allocates the options.
+		t.Options = []*TableOption{}
+	}
+	// the indexes are nested under the table, so they don't have the table ID in JSON.
+	for _, idx := range t.Indexes {
+		idx.Table = t.ID()
+	}
+	return nil
+}
+
+type jsonTableOption struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Quoted bool   `json:"quoted,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (opt *TableOption) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonTableOption{
+		Key:    opt.Key,
+		Value:  opt.Value,
+		Quoted: opt.NeedQuotes,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (opt *TableOption) UnmarshalJSON(data []byte) error {
+	var v jsonTableOption
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*opt = TableOption{
+		Key:        v.Key,
+		Value:      v.Value,
+		NeedQuotes: v.Quoted,
+	}
+	return nil
+}
+
+type jsonLength struct {
+	Length   string  `json:"length"`
+	Decimals *string `json:"decimals,omitempty"`
+}
+
+type jsonDefaultValue struct {
+	Value  string `json:"value"`
+	Quoted bool   `json:"quoted,omitempty"`
+}
+
+type jsonTableColumn struct {
+	Name          Ident             `json:"name"`
+	Type          ColumnType        `json:"type"`
+	Length        *jsonLength       `json:"length,omitempty"`
+	Unsigned      bool              `json:"unsigned,omitempty"`
+	ZeroFill      bool              `json:"zerofill,omitempty"`
+	Binary        bool              `json:"binary,omitempty"`
+	CharacterSet  *Ident            `json:"character_set,omitempty"`
+	Collation     *Ident            `json:"collation,omitempty"`
+	EnumValues    []string          `json:"enum_values,omitempty"`
+	SetValues     []string          `json:"set_values,omitempty"`
+	NullState     NullState         `json:"null_state,omitempty"`
+	Default       *jsonDefaultValue `json:"default,omitempty"`
+	AutoUpdate    *string           `json:"on_update,omitempty"`
+	AutoIncrement bool              `json:"auto_increment,omitempty"`
+	SRID          *int64            `json:"srid,omitempty"`
+	Key           bool              `json:"key,omitempty"`
+	Primary       bool              `json:"primary,omitempty"`
+	Unique        bool              `json:"unique,omitempty"`
+	Comment       *string           `json:"comment,omitempty"`
+	RenamedFrom   *Ident            `json:"renamed_from,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t *TableColumn) MarshalJSON() ([]byte, error) {
+	v := jsonTableColumn{
+		Name:          t.Name,
+		Type:          t.Type,
+		Unsigned:      t.Unsigned,
+		ZeroFill:      t.ZeroFill,
+		Binary:        t.Binary,
+		CharacterSet:  maybeIdentPtr(t.CharacterSet),
+		Collation:     maybeIdentPtr(t.Collation),
+		EnumValues:    t.EnumValues,
+		SetValues:     t.SetValues,
+		NullState:     t.NullState,
+		AutoUpdate:    maybeStringPtr(t.AutoUpdate),
+		AutoIncrement: t.AutoIncrement,
+		Key:           t.Key,
+		Primary:       t.Primary,
+		Unique:        t.Unique,
+		Comment:       maybeStringPtr(t.Comment),
+		RenamedFrom:   maybeIdentPtr(t.RenamedFrom),
+	}
+	if t.Length != nil {
+		v.Length = &jsonLength{
+			Length:   t.Length.Length,
+			Decimals: maybeStringPtr(t.Length.Decimals),
+		}
+	}
+	if t.Default.Valid {
+		v.Default = &jsonDefaultValue{
+			Value:  t.Default.Value,
+			Quoted: t.Default.Quoted,
+		}
+	}
+	if t.SRID.Valid {
+		v.SRID = &t.SRID.Value
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *TableColumn) UnmarshalJSON(data []byte) error {
+	var v jsonTableColumn
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*t = TableColumn{
+		Name:          v.Name,
+		Type:          v.Type,
+		Unsigned:      v.Unsigned,
+		ZeroFill:      v.ZeroFill,
+		Binary:        v.Binary,
+		CharacterSet:  maybeIdentFromPtr(v.CharacterSet),
+		Collation:     maybeIdentFromPtr(v.Collation),
+		EnumValues:    v.EnumValues,
+		SetValues:     v.SetValues,
+		NullState:     v.NullState,
+		AutoUpdate:    maybeStringFromPtr(v.AutoUpdate),
+		AutoIncrement: v.AutoIncrement,
+		Key:           v.Key,
+		Primary:       v.Primary,
+		Unique:        v.Unique,
+		Comment:       maybeStringFromPtr(v.Comment),
+		RenamedFrom:   maybeIdentFromPtr(v.RenamedFrom),
+	}
+	if v.Length != nil {
+		t.Length = &Length{
+			Length:   v.Length.Length,
+			Decimals: maybeStringFromPtr(v.Length.Decimals),
+		}
+	}
+	if v.Default != nil {
+		t.Default = DefaultValue{
+			Valid:  true,
+			Value:  v.Default.Value,
+			Quoted: v.Default.Quoted,
+		}
+	}
+	if v.SRID != nil {
+		t.SRID = MaybeInteger{Valid: true, Value: *v.SRID}
+	}
+	return nil
+}
+
+type jsonIndexColumn struct {
+	Name          Ident                    `json:"name"`
+	Length        *string                  `json:"length,omitempty"`
+	SortDirection IndexColumnSortDirection `json:"sort_direction,omitempty"`
+}
+
+type jsonIndexOption struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Quoted bool   `json:"quoted,omitempty"`
+}
+
+type jsonIndex struct {
+	Kind           IndexKind          `json:"kind"`
+	Type           IndexType          `json:"type,omitempty"`
+	Name           *Ident             `json:"name,omitempty"`
+	ConstraintName *Ident             `json:"constraint_name,omitempty"`
+	Columns        []*jsonIndexColumn `json:"columns"`
+	Reference      *Reference         `json:"reference,omitempty"`
+	Options        []*jsonIndexOption `json:"options,omitempty"`
+}
+
+func indexColumnsToJSON(cols []*IndexColumn) []*jsonIndexColumn {
+	ret := make([]*jsonIndexColumn, 0, len(cols))
+	for _, col := range cols {
+		ret = append(ret, &jsonIndexColumn{
+			Name:          col.Name,
+			Length:        maybeStringPtr(col.Length),
+			SortDirection: col.SortDirection,
+		})
+	}
+	return ret
+}
+
+func indexColumnsFromJSON(cols []*jsonIndexColumn) []*IndexColumn {
+	if cols == nil {
+		return nil
+	}
+	ret := make([]*IndexColumn, 0, len(cols))
+	for _, col := range cols {
+		ret = append(ret, &IndexColumn{
+			Name:          col.Name,
+			Length:        maybeStringFromPtr(col.Length),
+			SortDirection: col.SortDirection,
+		})
+	}
+	return ret
+}
+
+// MarshalJSON implements json.Marshaler.
+func (idx *Index) MarshalJSON() ([]byte, error) {
+	v := jsonIndex{
+		Kind:           idx.Kind,
+		Type:           idx.Type,
+		Name:           maybeIdentPtr(idx.Name),
+		ConstraintName: maybeIdentPtr(idx.ConstraintName),
+		Columns:        indexColumnsToJSON(idx.Columns),
+		Reference:      idx.Reference,
+	}
+	for _, opt := range idx.Options {
+		v.Options = append(v.Options, &jsonIndexOption{
+			Key:    opt.Key,
+			Value:  opt.Value,
+			Quoted: opt.NeedQuotes,
+		})
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (idx *Index) UnmarshalJSON(data []byte) error {
+	var v jsonIndex
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*idx = Index{
+		Kind:           v.Kind,
+		Type:           v.Type,
+		Name:           maybeIdentFromPtr(v.Name),
+		ConstraintName: maybeIdentFromPtr(v.ConstraintName),
+		Columns:        indexColumnsFromJSON(v.Columns),
+		Reference:      v.Reference,
+	}
+	for _, opt := range v.Options {
+		idx.Options = append(idx.Options, NewIndexOption(opt.Key, opt.Value, opt.Quoted))
+	}
+	return nil
+}
+
+type jsonReference struct {
+	TableName Ident              `json:"table_name"`
+	Columns   []*jsonIndexColumn `json:"columns"`
+	Match     ReferenceMatch     `json:"match,omitempty"`
+	OnDelete  ReferenceOption    `json:"on_delete,omitempty"`
+	OnUpdate  ReferenceOption    `json:"on_update,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r *Reference) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonReference{
+		TableName: r.TableName,
+		Columns:   indexColumnsToJSON(r.Columns),
+		Match:     r.Match,
+		OnDelete:  r.OnDelete,
+		OnUpdate:  r.OnUpdate,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *Reference) UnmarshalJSON(data []byte) error {
+	var v jsonReference
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*r = Reference{
+		TableName: v.TableName,
+		Columns:   indexColumnsFromJSON(v.Columns),
+		Match:     v.Match,
+		OnDelete:  v.OnDelete,
+		OnUpdate:  v.OnUpdate,
+	}
+	return nil
+}
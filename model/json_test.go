@@ -0,0 +1,83 @@
+package model_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/schemalex-deploy"
+	"github.com/shogo82148/schemalex-deploy/model"
+)
+
+func TestStmtsJSON(t *testing.T) {
+	const sql = "CREATE TABLE `f` ( `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT, PRIMARY KEY (`id`) ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;\n" +
+		"CREATE TABLE `fuga` (" +
+		"`id` INTEGER NOT NULL AUTO_INCREMENT, " +
+		"`fid` BIGINT UNSIGNED, " +
+		"`name` VARCHAR (255) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL DEFAULT '' COMMENT 'the name', " +
+		"`price` DECIMAL (10,2) NOT NULL DEFAULT 0, " +
+		"`kind` ENUM('a','b') NOT NULL, " +
+		"`point` POINT NOT NULL SRID 4326, " +
+		"`updated_at` DATETIME (6) NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP, " +
+		"PRIMARY KEY (`id`), " +
+		"INDEX `idx_name` USING BTREE (`name`(10), `price` DESC), " +
+		"SPATIAL INDEX `idx_point` (`point`), " +
+		"CONSTRAINT `fk` FOREIGN KEY (`fid`) REFERENCES `f` (`id`) ON DELETE CASCADE" +
+		") COMMENT 'fuga table';\n"
+
+	stmts, err := schemalex.New().ParseString(sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(stmts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got model.Stmts
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(stmts, got); diff != "" {
+		t.Errorf("round trip mismatch (-want/+got):\n%s", diff)
+	}
+}
+
+func TestTableColumnJSON(t *testing.T) {
+	col := &model.TableColumn{
+		Name:      "id",
+		Type:      model.ColumnTypeInt,
+		Length:    model.NewLength("10"),
+		Unsigned:  true,
+		NullState: model.NullStateNotNull,
+		Default:   model.DefaultValue{Valid: true, Value: "0"},
+		Comment:   model.MaybeString{Valid: true, Value: "identifier"},
+	}
+	data, err := json.Marshal(col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"name":"id","type":"INT","length":{"length":"10"},"unsigned":true,"null_state":"NOT NULL","default":{"value":"0"},"comment":"identifier"}`
+	if diff := cmp.Diff(want, string(data)); diff != "" {
+		t.Errorf("mismatch (-want/+got):\n%s", diff)
+	}
+}
+
+func TestIndexJSON(t *testing.T) {
+	idx := model.NewIndex(model.IndexKindForeignKey, "table#fuga")
+	idx.ConstraintName = model.MaybeIdent{Ident: "fk", Valid: true}
+	idx.Columns = []*model.IndexColumn{model.NewIndexColumn("fid")}
+	idx.Reference = model.NewReferenceTo("f", "id")
+	idx.Reference.OnDelete = model.ReferenceOptionSetNull
+
+	data, err := json.Marshal(idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"kind":"FOREIGN KEY","constraint_name":"fk","columns":[{"name":"fid"}],` +
+		`"reference":{"table_name":"f","columns":[{"name":"id"}],"on_delete":"SET NULL"}}`
+	if diff := cmp.Diff(want, string(data)); diff != "" {
+		t.Errorf("mismatch (-want/+got):\n%s", diff)
+	}
+}
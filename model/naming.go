@@ -0,0 +1,152 @@
+package model
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// primaryKeyName is the name of the primary key, that no other index can use.
+const primaryKeyName = "PRIMARY"
+
+// NameIndexes returns a copy of the table whose indexes and foreign keys all have their names.
+// The names of unnamed ones are assigned in the same way as MySQL does:
+//
+//   - An index is named after its first column, with a suffix _2, _3, ... if the name is already used.
+//   - A foreign key is named <table>_ibfk_N, N is a sequence number.
+//   - If no index can be used for a foreign key, the index implicitly created
+//     for the foreign key is added.
+//   - The index that the parser adds for a foreign key with the constraint symbol is removed
+//     if another index can be used for the foreign key.
+//
+// The table should be normalized as the parser does.
+func (t *Table) NameIndexes() *Table {
+	tbl := t.Clone()
+	if tbl.LikeTable.Valid {
+		return tbl
+	}
+
+	// the parser adds an index for each foreign key with the constraint symbol,
+	// but MySQL doesn't create it if another index can be used for the foreign key, e.g.
+	// SHOW CREATE TABLE shows KEY `pid` (`pid`), CONSTRAINT `t_ibfk_1` FOREIGN KEY (`pid`) ...
+	var implicit, explicit []*Index
+	for _, idx := range tbl.Indexes {
+		if isImplicitIndex(tbl, idx) {
+			implicit = append(implicit, idx)
+		} else {
+			explicit = append(explicit, idx)
+		}
+	}
+	for _, idx := range implicit {
+		if hasIndexForColumns(explicit, idx.Columns) {
+			tbl.Indexes = slices.DeleteFunc(tbl.Indexes, func(other *Index) bool { return other == idx })
+		}
+	}
+
+	used := make(map[string]struct{}, len(tbl.Indexes))
+	uniqueName := func(name Ident) Ident {
+		if !isKeyNameUsed(used, name) {
+			return name
+		}
+		for i := 2; ; i++ {
+			candidate := Ident(string(name) + "_" + strconv.Itoa(i))
+			if !isKeyNameUsed(used, candidate) {
+				return candidate
+			}
+		}
+	}
+
+	var fks []*Index
+	for _, idx := range tbl.Indexes {
+		switch idx.Kind {
+		case IndexKindForeignKey:
+			fks = append(fks, idx)
+			continue
+		case IndexKindPrimaryKey:
+			continue
+		}
+		if !idx.Name.Valid && idx.ConstraintName.Valid {
+			// CONSTRAINT symbol UNIQUE (...) is named after the symbol.
+			idx.Name = idx.ConstraintName
+		}
+		if !idx.Name.Valid && len(idx.Columns) > 0 {
+			idx.Name = MaybeIdent{Ident: uniqueName(idx.Columns[0].Name), Valid: true}
+		}
+		if idx.Name.Valid {
+			used[strings.ToLower(string(idx.Name.Ident))] = struct{}{}
+		}
+	}
+
+	// MySQL adds the indexes for the foreign keys after the other indexes.
+	prefix := strings.ToLower(string(tbl.Name)) + "_ibfk_"
+	var seq int
+	for _, fk := range fks {
+		if fk.ConstraintName.Valid {
+			// MySQL continues numbering from the largest number.
+			lower := strings.ToLower(string(fk.ConstraintName.Ident))
+			if n, err := strconv.Atoi(strings.TrimPrefix(lower, prefix)); err == nil && strings.HasPrefix(lower, prefix) {
+				seq = max(seq, n)
+			}
+		}
+	}
+	for _, fk := range fks {
+		if !hasIndexForForeignKey(tbl, fk) && len(fk.Columns) > 0 {
+			// the index is named after the constraint symbol, the index name, or the first column.
+			name := fk.Columns[0].Name
+			if fk.ConstraintName.Valid {
+				name = fk.ConstraintName.Ident
+			} else if fk.Name.Valid {
+				name = fk.Name.Ident
+			}
+			index := NewIndex(IndexKindNormal, tbl.ID())
+			index.Pos = fk.Pos
+			index.Name = MaybeIdent{Ident: uniqueName(name), Valid: true}
+			index.Type = fk.Type
+			index.Columns = cloneIndexColumns(fk.Columns)
+			used[strings.ToLower(string(index.Name.Ident))] = struct{}{}
+			tbl.Indexes = append(tbl.Indexes, index)
+		}
+		if !fk.ConstraintName.Valid {
+			// the index name of the foreign key is not used for the constraint.
+			seq++
+			fk.ConstraintName = MaybeIdent{Ident: Ident(string(tbl.Name) + "_ibfk_" + strconv.Itoa(seq)), Valid: true}
+		}
+	}
+	return tbl
+}
+
+func isKeyNameUsed(used map[string]struct{}, name Ident) bool {
+	if strings.EqualFold(string(name), primaryKeyName) {
+		return true
+	}
+	_, ok := used[strings.ToLower(string(name))]
+	return ok
+}
+
+// hasIndexForForeignKey returns whether the table has an index that the foreign key can use.
+// The index must have the columns of the foreign key as its first columns in the same order.
+func hasIndexForForeignKey(t *Table, fk *Index) bool {
+	return hasIndexForColumns(t.Indexes, fk.Columns)
+}
+
+// hasIndexForColumns returns whether one of the indexes has the columns as its first columns in the same order.
+func hasIndexForColumns(indexes []*Index, columns []*IndexColumn) bool {
+LOOP:
+	for _, idx := range indexes {
+		switch idx.Kind {
+		case IndexKindPrimaryKey, IndexKindNormal, IndexKindUnique:
+		default:
+			continue
+		}
+		if len(idx.Columns) < len(columns) {
+			continue
+		}
+		for i, col := range columns {
+			if !strings.EqualFold(string(idx.Columns[i].Name), string(col.Name)) || idx.Columns[i].Length.Valid {
+				continue LOOP
+			}
+		}
+		return true
+	}
+	return false
+}
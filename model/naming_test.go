@@ -0,0 +1,86 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/schemalex-deploy"
+	"github.com/shogo82148/schemalex-deploy/model"
+)
+
+func TestNameIndexes(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{
+			name: "first column",
+			sql:  "CREATE TABLE `hoge` ( `a` INTEGER, `b` INTEGER, INDEX (`a`, `b`), UNIQUE INDEX (`b`) )",
+			want: []string{"a", "b"},
+		},
+		{
+			name: "collision",
+			sql:  "CREATE TABLE `hoge` ( `a` INTEGER, `b` INTEGER, INDEX (`a`), INDEX (`a`, `b`), INDEX `a_3` (`b`), INDEX (`a`) )",
+			want: []string{"a", "a_2", "a_3", "a_4"},
+		},
+		{
+			name: "constraint symbol",
+			sql:  "CREATE TABLE `hoge` ( `a` INTEGER, CONSTRAINT `uniq` UNIQUE (`a`) )",
+			want: []string{"uniq"},
+		},
+		{
+			name: "primary",
+			sql:  "CREATE TABLE `hoge` ( `primary` INTEGER, PRIMARY KEY (`primary`), INDEX (`primary`) )",
+			want: []string{"", "primary_2"},
+		},
+		{
+			name: "foreign keys",
+			sql: "CREATE TABLE `hoge` ( `a` INTEGER, `b` INTEGER, `c` INTEGER, INDEX (`a`, `b`), " +
+				"FOREIGN KEY (`a`) REFERENCES `f` (`id`), " +
+				"CONSTRAINT `hoge_ibfk_3` FOREIGN KEY (`b`) REFERENCES `f` (`id`), " +
+				"FOREIGN KEY `fk` (`c`) REFERENCES `f` (`id`) )",
+			// the parser adds the index `hoge_ibfk_3` for the constraint `hoge_ibfk_3`.
+			want: []string{"a", "hoge_ibfk_4", "hoge_ibfk_3", "hoge_ibfk_3", "hoge_ibfk_5", "fk"},
+		},
+		{
+			name: "foreign key with an index",
+			sql: "CREATE TABLE `hoge` ( `a` INTEGER, KEY `a` (`a`), " +
+				"CONSTRAINT `hoge_ibfk_1` FOREIGN KEY (`a`) REFERENCES `f` (`id`) )",
+			// MySQL doesn't create the index `hoge_ibfk_1`, because the foreign key can use the index `a`.
+			want: []string{"a", "hoge_ibfk_1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmts, err := schemalex.New().ParseString(tt.sql)
+			if err != nil {
+				t.Fatal(err)
+			}
+			table := stmts[0].(*model.Table)
+			named := table.NameIndexes()
+
+			var got []string
+			for _, idx := range named.Indexes {
+				name := idx.Name
+				if idx.Kind == model.IndexKindForeignKey {
+					name = idx.ConstraintName
+				}
+				got = append(got, string(name.Ident))
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("mismatch (-want/+got):\n%s", diff)
+			}
+
+			// the original table is not changed.
+			orig, err := schemalex.New().ParseString(tt.sql)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(orig[0], table); diff != "" {
+				t.Errorf("the original table is changed (-want/+got):\n%s", diff)
+			}
+		})
+	}
+}
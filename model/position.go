@@ -0,0 +1,22 @@
+package model
+
+import "strconv"
+
+// Position describes a position in the source of the schema.
+// The zero value means that the position is unknown.
+type Position struct {
+	Line int // line number, starting at 1
+	Col  int // column number, same as schemalex.ParseError
+}
+
+// IsValid reports whether the position is known.
+func (pos Position) IsValid() bool {
+	return pos.Line > 0
+}
+
+func (pos Position) String() string {
+	if !pos.IsValid() {
+		return "unknown position"
+	}
+	return "line " + strconv.Itoa(pos.Line) + " column " + strconv.Itoa(pos.Col)
+}
@@ -3,6 +3,8 @@ package model
 import (
 	"crypto/sha256"
 	"fmt"
+	"slices"
+	"strings"
 )
 
 //go:generate go tool stringer -type=ReferenceMatch -output=reference_match_string_gen.go
@@ -61,3 +63,19 @@ func (r *Reference) ID() string {
 	}
 	return fmt.Sprintf("reference#%x", h.Sum(nil))
 }
+
+// Clone returns a deep copy of the reference.
+func (r *Reference) Clone() *Reference {
+	ref := *r
+	ref.Columns = cloneIndexColumns(r.Columns)
+	return &ref
+}
+
+// Equal returns whether r and other refer the same columns with the same options.
+func (r *Reference) Equal(other *Reference) bool {
+	return strings.EqualFold(string(r.TableName), string(other.TableName)) &&
+		slices.EqualFunc(r.Columns, other.Columns, (*IndexColumn).Equal) &&
+		r.Match == other.Match &&
+		r.OnDelete == other.OnDelete &&
+		r.OnUpdate == other.OnUpdate
+}
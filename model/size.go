@@ -0,0 +1,422 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+)
+
+// The limits of InnoDB with the default page size (16KB).
+// https://dev.mysql.com/doc/refman/8.0/en/innodb-limits.html
+const (
+	// MaxKeyLength is the maximum length of an index key in bytes.
+	MaxKeyLength = 3072
+
+	// MaxKeyPartLengthCompact is the maximum length of an index key part in bytes
+	// for the tables that use the REDUNDANT or COMPACT row format.
+	MaxKeyPartLengthCompact = 767
+
+	// MaxRowSize is the maximum row size of MySQL in bytes, not counting BLOBs.
+	MaxRowSize = 65535
+
+	// MaxInnoDBRowSize is the maximum size of a row that InnoDB stores in a page, in bytes.
+	MaxInnoDBRowSize = 8126
+)
+
+// DefaultCharset is the default character set of MySQL 8.0.
+const DefaultCharset = "utf8mb4"
+
+// DefaultRowFormat is the default row format of InnoDB.
+const DefaultRowFormat = "DYNAMIC"
+
+// charsetMaxLen is the maximum length of a character in bytes.
+var charsetMaxLen = map[string]int{
+	"armscii8": 1,
+	"ascii":    1,
+	"big5":     2,
+	"binary":   1,
+	"cp1250":   1,
+	"cp1251":   1,
+	"cp1256":   1,
+	"cp1257":   1,
+	"cp850":    1,
+	"cp852":    1,
+	"cp866":    1,
+	"cp932":    2,
+	"dec8":     1,
+	"eucjpms":  3,
+	"euckr":    2,
+	"gb18030":  4,
+	"gb2312":   2,
+	"gbk":      2,
+	"geostd8":  1,
+	"greek":    1,
+	"hebrew":   1,
+	"hp8":      1,
+	"keybcs2":  1,
+	"koi8r":    1,
+	"koi8u":    1,
+	"latin1":   1,
+	"latin2":   1,
+	"latin5":   1,
+	"latin7":   1,
+	"macce":    1,
+	"macroman": 1,
+	"sjis":     2,
+	"swe7":     1,
+	"tis620":   1,
+	"ucs2":     2,
+	"ujis":     3,
+	"utf16":    4,
+	"utf16le":  4,
+	"utf32":    4,
+	"utf8":     3,
+	"utf8mb3":  3,
+	"utf8mb4":  4,
+}
+
+// CharsetMaxLen returns the maximum length of a character of the character set in bytes.
+// It returns 4 for unknown character sets, it is the largest among the known ones.
+func CharsetMaxLen(charset Ident) int {
+	if n, ok := charsetMaxLen[strings.ToLower(string(charset))]; ok {
+		return n
+	}
+	return 4
+}
+
+// charsetOfCollation returns the character set of the collation, e.g. utf8mb4 for utf8mb4_bin.
+func charsetOfCollation(collation Ident) Ident {
+	name := string(collation)
+	if i := strings.IndexByte(name, '_'); i > 0 {
+		return Ident(name[:i])
+	}
+	return collation
+}
+
+// LookupOption looks for a table option with the given key, ignoring the case.
+func (t *Table) LookupOption(key string) (*TableOption, bool) {
+	for _, opt := range t.Options {
+		if strings.EqualFold(opt.Key, key) {
+			return opt, true
+		}
+	}
+	return nil, false
+}
+
+// Charset returns the default character set of the table.
+// If the table doesn't have it, it returns DefaultCharset.
+func (t *Table) Charset() Ident {
+	if opt, ok := t.LookupOption("DEFAULT CHARACTER SET"); ok {
+		return Ident(opt.Value)
+	}
+	if opt, ok := t.LookupOption("DEFAULT COLLATE"); ok {
+		return charsetOfCollation(Ident(opt.Value))
+	}
+	return DefaultCharset
+}
+
+// RowFormat returns the row format of the table in upper case.
+// If the table doesn't have it, it returns DefaultRowFormat.
+func (t *Table) RowFormat() string {
+	if opt, ok := t.LookupOption("ROW_FORMAT"); ok && !strings.EqualFold(opt.Value, "DEFAULT") {
+		return strings.ToUpper(opt.Value)
+	}
+	return DefaultRowFormat
+}
+
+// ColumnCharset returns the effective character set of the column in the table.
+func (t *Table) ColumnCharset(col *TableColumn) Ident {
+	if col.CharacterSet.Valid {
+		return col.CharacterSet.Ident
+	}
+	if col.Collation.Valid {
+		return charsetOfCollation(col.Collation.Ident)
+	}
+	return t.Charset()
+}
+
+// IsText returns whether the type stores characters.
+func (c ColumnType) IsText() bool {
+	switch c {
+	case ColumnTypeChar, ColumnTypeVarChar,
+		ColumnTypeTinyText, ColumnTypeText, ColumnTypeMediumText, ColumnTypeLongText,
+		ColumnTypeEnum, ColumnTypeSet:
+		return true
+	}
+	return false
+}
+
+// IsBlob returns whether the type is stored separately from the row, such as BLOB and TEXT.
+func (c ColumnType) IsBlob() bool {
+	switch c {
+	case ColumnTypeTinyBlob, ColumnTypeBlob, ColumnTypeMediumBlob, ColumnTypeLongBlob,
+		ColumnTypeTinyText, ColumnTypeText, ColumnTypeMediumText, ColumnTypeLongText,
+		ColumnTypeJSON, ColumnTypeGeometry, ColumnTypePoint, ColumnTypeLineString,
+		ColumnTypePolygon, ColumnTypeMultiPoint, ColumnTypeMultiLineString,
+		ColumnTypeMultiPolygon, ColumnTypeGeometryCollection:
+		return true
+	}
+	return false
+}
+
+// ColumnSize describes the size of a column.
+type ColumnSize struct {
+	Column *TableColumn
+
+	// MaxBytes is the maximum length of the value in bytes.
+	// It doesn't include the length bytes of variable-length types.
+	// It is the maximum length of the type for BLOBs and TEXTs.
+	MaxBytes int
+
+	// RowBytes is the number of bytes that the column uses in the row,
+	// counted in the same way as the MySQL limit of 65,535 bytes.
+	RowBytes int
+
+	// Variable reports whether the column has variable length.
+	Variable bool
+}
+
+// IndexSize describes the size of an index key.
+type IndexSize struct {
+	Index *Index
+
+	// Parts are the sizes of the key parts in bytes.
+	// It is -1 if the key part is a BLOB or TEXT without prefix length.
+	Parts []int
+
+	// Bytes is the total size of the key in bytes.
+	Bytes int
+}
+
+// TableSize describes the size of a table.
+type TableSize struct {
+	Table     *Table
+	RowFormat string
+	Columns   []*ColumnSize
+	Indexes   []*IndexSize
+
+	// RowBytes is the size of the row in bytes, counted in the same way as
+	// the MySQL limit of 65,535 bytes. BLOBs and TEXTs count only their pointers.
+	RowBytes int
+
+	// InnoDBRowBytes is the estimated minimum size of the row that InnoDB stores in a page.
+	// The long variable-length columns count only the bytes stored in line.
+	InnoDBRowBytes int
+}
+
+// AnalyzeSize calculates the sizes of the columns, the index keys, and the row of the table.
+// The table should be normalized as the parser does.
+func AnalyzeSize(t *Table) *TableSize {
+	size := &TableSize{
+		Table:     t,
+		RowFormat: t.RowFormat(),
+	}
+
+	// the maximum bytes that InnoDB stores in line for long columns.
+	localBytes := 40 // 20 bytes pointer * 2 for DYNAMIC and COMPRESSED
+	switch size.RowFormat {
+	case "REDUNDANT", "COMPACT":
+		localBytes = 768 + 20 // 768 bytes prefix + 20 bytes pointer
+	}
+
+	nullable := 0
+	for _, col := range t.Columns {
+		cs := t.columnSize(col)
+		size.Columns = append(size.Columns, cs)
+		size.RowBytes += cs.RowBytes
+		if col.NullState != NullStateNotNull {
+			nullable++
+		}
+
+		if cs.Variable {
+			// length bytes
+			if cs.MaxBytes > 255 {
+				size.InnoDBRowBytes += 2
+			} else {
+				size.InnoDBRowBytes++
+			}
+			if cs.MaxBytes > localBytes && (col.Type.IsBlob() || cs.MaxBytes > 255) {
+				size.InnoDBRowBytes += localBytes
+				continue
+			}
+		}
+		size.InnoDBRowBytes += cs.MaxBytes
+	}
+	nullBytes := (nullable + 7) / 8
+	size.RowBytes += nullBytes
+	// the record header, DB_TRX_ID and DB_ROLL_PTR
+	size.InnoDBRowBytes += nullBytes + 5 + 6 + 7
+
+	for _, idx := range t.Indexes {
+		switch idx.Kind {
+		case IndexKindPrimaryKey, IndexKindNormal, IndexKindUnique:
+		default:
+			// FULLTEXT and SPATIAL have their own structures.
+			// FOREIGN KEY uses other indexes.
+			continue
+		}
+		is := &IndexSize{Index: idx}
+		for _, part := range idx.Columns {
+			n := t.keyPartSize(part)
+			is.Parts = append(is.Parts, n)
+			if n > 0 {
+				is.Bytes += n
+			}
+		}
+		size.Indexes = append(size.Indexes, is)
+	}
+	return size
+}
+
+func (t *Table) keyPartSize(part *IndexColumn) int {
+	col, ok := t.LookupColumn(columnID(part.Name))
+	if !ok {
+		return 0
+	}
+	cs := t.columnSize(col)
+	if !part.Length.Valid {
+		if col.Type.IsBlob() {
+			return -1
+		}
+		return cs.MaxBytes
+	}
+	n, _ := strconv.Atoi(part.Length.Value)
+	if col.Type.IsText() {
+		n *= CharsetMaxLen(t.ColumnCharset(col))
+	}
+	if n > cs.MaxBytes {
+		n = cs.MaxBytes
+	}
+	return n
+}
+
+func (t *Table) columnSize(col *TableColumn) *ColumnSize {
+	length := -1
+	decimals := -1
+	if col.Length != nil {
+		if n, err := strconv.Atoi(col.Length.Length); err == nil {
+			length = n
+		}
+		if col.Length.Decimals.Valid {
+			if n, err := strconv.Atoi(col.Length.Decimals.Value); err == nil {
+				decimals = n
+			}
+		}
+	}
+	mb := 1
+	if col.Type.IsText() {
+		mb = CharsetMaxLen(t.ColumnCharset(col))
+	}
+
+	cs := &ColumnSize{Column: col}
+	fixed := func(n int) *ColumnSize {
+		cs.MaxBytes = n
+		cs.RowBytes = n
+		return cs
+	}
+	variable := func(n int) *ColumnSize {
+		cs.MaxBytes = n
+		cs.RowBytes = n + 1
+		if n > 255 {
+			cs.RowBytes = n + 2
+		}
+		cs.Variable = true
+		return cs
+	}
+	blob := func(lengthBytes, max int) *ColumnSize {
+		cs.MaxBytes = max
+		cs.RowBytes = lengthBytes + 8 // length bytes + pointer
+		cs.Variable = true
+		return cs
+	}
+
+	switch col.Type.SynonymType() {
+	case ColumnTypeTinyInt, ColumnTypeYear:
+		return fixed(1)
+	case ColumnTypeSmallInt:
+		return fixed(2)
+	case ColumnTypeMediumInt, ColumnTypeDate:
+		return fixed(3)
+	case ColumnTypeInt:
+		return fixed(4)
+	case ColumnTypeBigInt, ColumnTypeDouble:
+		return fixed(8)
+	case ColumnTypeFloat:
+		if decimals < 0 && length > 24 {
+			// FLOAT(p) is DOUBLE if p > 24
+			return fixed(8)
+		}
+		return fixed(4)
+	case ColumnTypeDecimal:
+		if length < 0 {
+			length = 10
+		}
+		if decimals < 0 {
+			decimals = 0
+		}
+		return fixed(decimalBytes(length-decimals) + decimalBytes(decimals))
+	case ColumnTypeTime:
+		return fixed(3 + fractionalBytes(length))
+	case ColumnTypeDateTime:
+		return fixed(5 + fractionalBytes(length))
+	case ColumnTypeTimestamp:
+		return fixed(4 + fractionalBytes(length))
+	case ColumnTypeBit:
+		if length < 0 {
+			length = 1
+		}
+		return fixed((length + 7) / 8)
+	case ColumnTypeChar:
+		if length < 0 {
+			length = 1
+		}
+		return fixed(length * mb)
+	case ColumnTypeBinary:
+		if length < 0 {
+			length = 1
+		}
+		return fixed(length)
+	case ColumnTypeVarChar:
+		return variable(max(length, 0) * mb)
+	case ColumnTypeVarBinary:
+		return variable(max(length, 0))
+	case ColumnTypeEnum:
+		if len(col.EnumValues) > 255 {
+			return fixed(2)
+		}
+		return fixed(1)
+	case ColumnTypeSet:
+		switch n := (len(col.SetValues) + 7) / 8; {
+		case n <= 4:
+			return fixed(n)
+		default:
+			return fixed(8)
+		}
+	case ColumnTypeTinyBlob:
+		return blob(1, 1<<8-1)
+	case ColumnTypeTinyText:
+		return blob(1, 1<<8-1)
+	case ColumnTypeBlob, ColumnTypeText:
+		return blob(2, 1<<16-1)
+	case ColumnTypeMediumBlob, ColumnTypeMediumText:
+		return blob(3, 1<<24-1)
+	default:
+		// LONGBLOB, LONGTEXT, JSON and spatial types
+		return blob(4, 1<<32-1)
+	}
+}
+
+// decimalBytes returns the number of bytes to store the digits of DECIMAL.
+// https://dev.mysql.com/doc/refman/8.0/en/precision-math-decimal-characteristics.html
+func decimalBytes(digits int) int {
+	leftover := [...]int{0, 1, 1, 2, 2, 3, 3, 4, 4}
+	return digits/9*4 + leftover[digits%9]
+}
+
+// fractionalBytes returns the number of bytes to store the fractional seconds.
+// https://dev.mysql.com/doc/refman/8.0/en/storage-requirements.html#data-types-storage-reqs-date-time
+func fractionalBytes(fsp int) int {
+	if fsp <= 0 {
+		return 0
+	}
+	return (fsp + 1) / 2
+}
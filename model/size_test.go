@@ -0,0 +1,87 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/schemalex-deploy"
+	"github.com/shogo82148/schemalex-deploy/model"
+)
+
+func TestAnalyzeSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		sql       string
+		columns   []int
+		indexes   [][]int
+		rowBytes  int
+		innoDB    int
+		rowFormat string
+	}{
+		{
+			name: "numbers",
+			sql: "CREATE TABLE `hoge` ( `a` TINYINT NOT NULL, `b` INT NOT NULL, `c` BIGINT NOT NULL, " +
+				"`d` DECIMAL(10, 2) NOT NULL, `e` DOUBLE NOT NULL, `f` DATETIME(6) NOT NULL, " +
+				"PRIMARY KEY (`c`), INDEX (`a`, `b`) )",
+			columns:   []int{1, 4, 8, 5, 8, 8},
+			indexes:   [][]int{{8}, {1, 4}},
+			rowBytes:  34,
+			innoDB:    34 + 5 + 6 + 7,
+			rowFormat: "DYNAMIC",
+		},
+		{
+			name: "strings",
+			sql: "CREATE TABLE `hoge` ( `a` CHAR(10) CHARACTER SET latin1, `b` VARCHAR(100), " +
+				"`c` VARCHAR(300) COLLATE utf8mb3_bin, `d` TEXT, " +
+				"INDEX (`a`, `b`(10)), INDEX (`d`(20)) ) DEFAULT CHARSET=utf8mb4 ROW_FORMAT=COMPACT",
+			columns:   []int{10, 400, 900, 65535},
+			indexes:   [][]int{{10, 40}, {80}},
+			rowBytes:  10 + 402 + 902 + 10 + 1,
+			innoDB:    10 + (2 + 400) + (2 + 788) + (2 + 788) + 1 + 5 + 6 + 7,
+			rowFormat: "COMPACT",
+		},
+		{
+			name:      "dynamic row format",
+			sql:       "CREATE TABLE `hoge` ( `a` VARCHAR(10) NOT NULL, `b` VARCHAR(300) NOT NULL, `c` BLOB NOT NULL, INDEX (`c`) )",
+			columns:   []int{40, 1200, 65535},
+			indexes:   [][]int{{-1}},
+			rowBytes:  41 + 1202 + 10,
+			innoDB:    (1 + 40) + (2 + 40) + (2 + 40) + 5 + 6 + 7,
+			rowFormat: "DYNAMIC",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmts, err := schemalex.New().ParseString(tt.sql)
+			if err != nil {
+				t.Fatal(err)
+			}
+			size := model.AnalyzeSize(stmts[0].(*model.Table))
+
+			var columns []int
+			for _, cs := range size.Columns {
+				columns = append(columns, cs.MaxBytes)
+			}
+			if diff := cmp.Diff(tt.columns, columns); diff != "" {
+				t.Errorf("columns mismatch (-want/+got):\n%s", diff)
+			}
+			var indexes [][]int
+			for _, is := range size.Indexes {
+				indexes = append(indexes, is.Parts)
+			}
+			if diff := cmp.Diff(tt.indexes, indexes); diff != "" {
+				t.Errorf("indexes mismatch (-want/+got):\n%s", diff)
+			}
+			if size.RowBytes != tt.rowBytes {
+				t.Errorf("want row bytes %d, got %d", tt.rowBytes, size.RowBytes)
+			}
+			if size.InnoDBRowBytes != tt.innoDB {
+				t.Errorf("want InnoDB row bytes %d, got %d", tt.innoDB, size.InnoDBRowBytes)
+			}
+			if size.RowFormat != tt.rowFormat {
+				t.Errorf("want row format %q, got %q", tt.rowFormat, size.RowFormat)
+			}
+		})
+	}
+}
@@ -11,6 +11,14 @@ type Table struct {
 	Columns     []*TableColumn
 	Indexes     []*Index
 	Options     []*TableOption
+
+	// RenamedFrom is the old name of the table.
+	// It is written in the schema as "-- schemalex:renamed-from old_name" before CREATE TABLE.
+	RenamedFrom MaybeIdent
+
+	// Pos is the position of the table in the source.
+	// It is set only if the parser records positions.
+	Pos Position
 }
 
 // NewTable create a new table with the given name
@@ -72,17 +80,21 @@ func (t *Table) Normalize() *Table {
 		// column_definition [UNIQUE [KEY] | [PRIMARY] KEY]
 		// they mean same as INDEX or CONSTRAINT
 		switch {
-		case ncol.Primary:
+		case ncol.Primary, ncol.Key:
 			// we have to move off the index declaration from the
-			// primary key column to an index associated with the table
+			// primary key column to an index associated with the table.
+			// KEY in the column definition means PRIMARY KEY.
 			index := NewIndex(IndexKindPrimaryKey, t.ID())
+			index.Pos = ncol.Pos
 			index.Type = IndexTypeNone
 			idxCol := NewIndexColumn(ncol.Name)
 			index.Columns = append(index.Columns, idxCol)
 			additionalIndexes = append(additionalIndexes, index)
 			ncol.Primary = false
+			ncol.Key = false
 		case ncol.Unique:
 			index := NewIndex(IndexKindUnique, t.ID())
+			index.Pos = ncol.Pos
 			// if you do not assign a name, the index is assigned the same name as the first indexed column
 			index.Name.Valid = true
 			index.Name.Ident = ncol.Name
@@ -112,10 +124,10 @@ func (t *Table) Normalize() *Table {
 			if _, ok := seen[nidx.ConstraintName.Ident]; !ok {
 				// add implicitly created INDEX
 				index := NewIndex(IndexKindNormal, t.ID())
+				index.Pos = nidx.Pos
 				index.Name = nidx.ConstraintName
 				index.Type = nidx.Type
-				index.Columns = make([]*IndexColumn, len(nidx.Columns))
-				copy(index.Columns, nidx.Columns)
+				index.Columns = cloneIndexColumns(nidx.Columns)
 				indexes = append(indexes, index)
 			}
 		}
@@ -128,11 +140,40 @@ func (t *Table) Normalize() *Table {
 	tbl.Temporary = t.Temporary
 	tbl.Indexes = append(additionalIndexes, indexes...)
 	tbl.Columns = columns
-	tbl.Options = make([]*TableOption, len(t.Options))
-	copy(tbl.Options, t.Options)
+	tbl.Options = cloneTableOptions(t.Options)
+	return &tbl
+}
+
+// Clone returns a deep copy of the table.
+func (t *Table) Clone() *Table {
+	tbl := *t
+	if t.Columns != nil {
+		tbl.Columns = make([]*TableColumn, len(t.Columns))
+		for i, col := range t.Columns {
+			tbl.Columns[i] = col.Clone()
+		}
+	}
+	if t.Indexes != nil {
+		tbl.Indexes = make([]*Index, len(t.Indexes))
+		for i, idx := range t.Indexes {
+			tbl.Indexes[i] = idx.Clone()
+		}
+	}
+	if t.Options != nil {
+		tbl.Options = cloneTableOptions(t.Options)
+	}
 	return &tbl
 }
 
+func cloneTableOptions(options []*TableOption) []*TableOption {
+	ret := make([]*TableOption, len(options))
+	for i, opt := range options {
+		o := *opt
+		ret[i] = &o
+	}
+	return ret
+}
+
 // TableOption describes a possible table option, such as `ENGINE=InnoDB`
 type TableOption struct {
 	Key        string
@@ -150,3 +191,9 @@ func NewTableOption(k, v string, q bool) *TableOption {
 }
 
 func (opt *TableOption) ID() string { return "tableopt#" + strings.ToLower(opt.Key) }
+
+// Equal returns whether opt and other have same key and value.
+// Whether the value needs quotes or not is not compared.
+func (opt *TableOption) Equal(other *TableOption) bool {
+	return strings.EqualFold(opt.Key, other.Key) && opt.Value == other.Value
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -59,6 +60,14 @@ type TableColumn struct {
 	Unsigned      bool
 	ZeroFill      bool
 	SRID          MaybeInteger
+
+	// RenamedFrom is the old name of the column.
+	// It is written in the schema as "/* schemalex:renamed-from old_name */" in the column definition.
+	RenamedFrom MaybeIdent
+
+	// Pos is the position of the column in the source.
+	// It is set only if the parser records positions.
+	Pos Position
 }
 
 // NewTableColumn creates a new TableColumn with the given name
@@ -117,6 +126,7 @@ func (t *TableColumn) Normalize() *TableColumn {
 	var synonym ColumnType
 	var removeQuotes bool
 	var setDefaultNull bool
+	var boolDefault string
 
 	if t.Length == nil {
 		if l := t.NativeLength(); l != nil {
@@ -149,13 +159,9 @@ func (t *TableColumn) Normalize() *TableColumn {
 		case ColumnTypeBool, ColumnTypeBoolean:
 			switch t.Default.Value {
 			case "TRUE":
-				t.Default.Valid = true
-				t.Default.Value = "1"
-				t.Default.Quoted = false
+				boolDefault = "1"
 			case "FALSE":
-				t.Default.Valid = true
-				t.Default.Value = "0"
-				t.Default.Quoted = false
+				boolDefault = "0"
 			}
 		}
 	} else {
@@ -172,7 +178,7 @@ func (t *TableColumn) Normalize() *TableColumn {
 		}
 	}
 
-	col := *t
+	col := t.Clone()
 	if length != nil {
 		col.Length = length
 	}
@@ -188,10 +194,155 @@ func (t *TableColumn) Normalize() *TableColumn {
 		col.Default.Quoted = false
 	}
 
+	if boolDefault != "" {
+		col.Default.Valid = true
+		col.Default.Value = boolDefault
+		col.Default.Quoted = false
+	}
+
 	if setDefaultNull {
 		col.Default.Valid = true
 		col.Default.Value = "NULL"
 		col.Default.Quoted = false
 	}
+	return col
+}
+
+// Clone returns a deep copy of the column.
+func (t *TableColumn) Clone() *TableColumn {
+	col := *t
+	if t.Length != nil {
+		l := *t.Length
+		col.Length = &l
+	}
+	col.EnumValues = slices.Clone(t.EnumValues)
+	col.SetValues = slices.Clone(t.SetValues)
 	return &col
 }
+
+// Equal returns whether the default values are same.
+// The quoting flag is taken into account only if it changes the meaning of
+// the value, e.g. `DEFAULT NULL` and `DEFAULT 'NULL'`.
+func (v DefaultValue) Equal(w DefaultValue) bool {
+	if v.Valid != w.Valid {
+		return false
+	}
+	if !v.Valid {
+		return true
+	}
+	if v.Value != w.Value {
+		return false
+	}
+	if v.Quoted != w.Quoted {
+		return !isDefaultKeyword(v.Value)
+	}
+	return true
+}
+
+// isDefaultKeyword returns whether s has special meaning if it is not quoted.
+func isDefaultKeyword(s string) bool {
+	upper := strings.ToUpper(s)
+	switch {
+	case upper == "NULL", upper == "TRUE", upper == "FALSE":
+		return true
+	case strings.HasPrefix(upper, "CURRENT_TIMESTAMP"), strings.HasPrefix(upper, "NOW"):
+		return true
+	case strings.HasPrefix(upper, "LOCALTIME"):
+		return true
+	case strings.HasPrefix(s, "("):
+		return true
+	}
+	return false
+}
+
+// Equal returns whether the lengths are same.
+func (l *Length) Equal(m *Length) bool {
+	if l == nil || m == nil {
+		return l == m
+	}
+	return l.Length == m.Length && l.Decimals == m.Decimals
+}
+
+// List of attributes of a column reported by TableColumn.Compare.
+const (
+	ColumnAttributeName          = "name"
+	ColumnAttributeType          = "type"
+	ColumnAttributeLength        = "length"
+	ColumnAttributeNullState     = "nullability"
+	ColumnAttributeCharacterSet  = "character set"
+	ColumnAttributeCollation     = "collation"
+	ColumnAttributeDefault       = "default"
+	ColumnAttributeComment       = "comment"
+	ColumnAttributeAutoUpdate    = "on update"
+	ColumnAttributeEnumValues    = "enum values"
+	ColumnAttributeSetValues     = "set values"
+	ColumnAttributeAutoIncrement = "auto_increment"
+	ColumnAttributeBinary        = "binary"
+	ColumnAttributeUnsigned      = "unsigned"
+	ColumnAttributeZeroFill      = "zerofill"
+	ColumnAttributeSRID          = "srid"
+)
+
+// Equal returns whether t and u have same definition.
+// It ignores the fields that are not a part of the column definition,
+// such as TableID and the index declarations (Key, Primary and Unique).
+func (t *TableColumn) Equal(u *TableColumn) bool {
+	return len(t.Compare(u)) == 0
+}
+
+// Compare compares t and u, and returns the list of attributes that differ.
+// The attributes are the ColumnAttribute* constants, and they are
+// in the order of the column definition.
+// If the columns have same definition, Compare returns nil.
+func (t *TableColumn) Compare(u *TableColumn) []string {
+	var diffs []string
+	if t.Name != u.Name {
+		diffs = append(diffs, ColumnAttributeName)
+	}
+	if t.Type != u.Type {
+		diffs = append(diffs, ColumnAttributeType)
+	}
+	if !t.Length.Equal(u.Length) {
+		diffs = append(diffs, ColumnAttributeLength)
+	}
+	if !slices.Equal(t.EnumValues, u.EnumValues) {
+		diffs = append(diffs, ColumnAttributeEnumValues)
+	}
+	if !slices.Equal(t.SetValues, u.SetValues) {
+		diffs = append(diffs, ColumnAttributeSetValues)
+	}
+	if t.Unsigned != u.Unsigned {
+		diffs = append(diffs, ColumnAttributeUnsigned)
+	}
+	if t.ZeroFill != u.ZeroFill {
+		diffs = append(diffs, ColumnAttributeZeroFill)
+	}
+	if t.Binary != u.Binary {
+		diffs = append(diffs, ColumnAttributeBinary)
+	}
+	if !equalMaybeIdentFold(t.CharacterSet, u.CharacterSet) {
+		diffs = append(diffs, ColumnAttributeCharacterSet)
+	}
+	if !equalMaybeIdentFold(t.Collation, u.Collation) {
+		diffs = append(diffs, ColumnAttributeCollation)
+	}
+	if t.AutoUpdate != u.AutoUpdate {
+		diffs = append(diffs, ColumnAttributeAutoUpdate)
+	}
+	if t.NullState != u.NullState {
+		diffs = append(diffs, ColumnAttributeNullState)
+	}
+	if t.SRID != u.SRID {
+		diffs = append(diffs, ColumnAttributeSRID)
+	}
+	if !t.Default.Equal(u.Default) {
+		diffs = append(diffs, ColumnAttributeDefault)
+	}
+	if t.AutoIncrement != u.AutoIncrement {
+		diffs = append(diffs, ColumnAttributeAutoIncrement)
+	}
+	if t.Comment != u.Comment {
+		diffs = append(diffs, ColumnAttributeComment)
+	}
+	return diffs
+}
@@ -210,3 +210,91 @@ func TestTableColumnNormalize(t *testing.T) {
 		})
 	}
 }
+
+func TestTableColumnCompare(t *testing.T) {
+	testCases := []struct {
+		name string
+		a, b *TableColumn
+		want []string
+	}{
+		{
+			name: "same",
+			a:    &TableColumn{Name: "foo", Type: ColumnTypeInt, Length: NewLength("11")},
+			b:    &TableColumn{Name: "foo", Type: ColumnTypeInt, Length: NewLength("11")},
+			want: nil,
+		},
+		{
+			name: "ignore irrelevant fields",
+			a:    &TableColumn{TableID: "table#foo", Name: "foo", Type: ColumnTypeInt, Key: true},
+			b:    &TableColumn{TableID: "table#bar", Name: "foo", Type: ColumnTypeInt},
+			want: nil,
+		},
+		{
+			name: "ignore quotes of literals",
+			a: &TableColumn{
+				Name:    "foo",
+				Type:    ColumnTypeVarChar,
+				Default: DefaultValue{Valid: true, Value: "0", Quoted: true},
+			},
+			b: &TableColumn{
+				Name:    "foo",
+				Type:    ColumnTypeVarChar,
+				Default: DefaultValue{Valid: true, Value: "0", Quoted: false},
+			},
+			want: nil,
+		},
+		{
+			name: "quoted NULL is not NULL",
+			a: &TableColumn{
+				Name:    "foo",
+				Type:    ColumnTypeVarChar,
+				Default: DefaultValue{Valid: true, Value: "NULL", Quoted: true},
+			},
+			b: &TableColumn{
+				Name:    "foo",
+				Type:    ColumnTypeVarChar,
+				Default: DefaultValue{Valid: true, Value: "NULL", Quoted: false},
+			},
+			want: []string{ColumnAttributeDefault},
+		},
+		{
+			name: "ignore the case of character sets",
+			a:    &TableColumn{Name: "foo", Type: ColumnTypeText, CharacterSet: MaybeIdent{Ident: "utf8mb4", Valid: true}},
+			b:    &TableColumn{Name: "foo", Type: ColumnTypeText, CharacterSet: MaybeIdent{Ident: "UTF8MB4", Valid: true}},
+			want: nil,
+		},
+		{
+			name: "multiple attributes",
+			a: &TableColumn{
+				Name:      "foo",
+				Type:      ColumnTypeInt,
+				Length:    NewLength("11"),
+				NullState: NullStateNone,
+			},
+			b: &TableColumn{
+				Name:      "foo",
+				Type:      ColumnTypeBigInt,
+				Length:    NewLength("20"),
+				NullState: NullStateNotNull,
+				Comment:   MaybeString{Valid: true, Value: "bar"},
+			},
+			want: []string{
+				ColumnAttributeType,
+				ColumnAttributeLength,
+				ColumnAttributeNullState,
+				ColumnAttributeComment,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.a.Compare(tc.b)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("mismatch (-want/+got)\n%s", diff)
+			}
+			if tc.a.Equal(tc.b) != (len(tc.want) == 0) {
+				t.Errorf("Equal and Compare are inconsistent")
+			}
+		})
+	}
+}
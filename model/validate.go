@@ -0,0 +1,362 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxIdentLength is the maximum length of identifiers, such as table names and column names.
+// https://dev.mysql.com/doc/refman/8.0/en/identifier-length.html
+const MaxIdentLength = 64
+
+// Severity describes how serious a diagnostic is.
+type Severity int
+
+// List of possible Severity values.
+const (
+	// SeverityError means MySQL will reject the schema.
+	SeverityError Severity = iota
+	// SeverityWarning means MySQL accepts the schema, but it may cause problems.
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// Diagnostic is a problem found in the schema.
+type Diagnostic struct {
+	// Pos is the position of the problem.
+	// It is valid only if the schema is parsed with schemalex.WithPositions.
+	Pos Position
+
+	Severity Severity
+
+	// Table is the name of the table that has the problem.
+	Table Ident
+
+	Message string
+}
+
+func (d *Diagnostic) String() string {
+	var buf strings.Builder
+	buf.WriteString(d.Severity.String())
+	if d.Pos.IsValid() {
+		buf.WriteString(" at ")
+		buf.WriteString(d.Pos.String())
+	}
+	if d.Table != "" {
+		buf.WriteString(" in table ")
+		buf.WriteString(d.Table.Quoted())
+	}
+	buf.WriteString(": ")
+	buf.WriteString(d.Message)
+	return buf.String()
+}
+
+// Diagnostics is a list of diagnostics.
+type Diagnostics []*Diagnostic
+
+// HasErrors returns whether ds contains any diagnostic with SeverityError.
+func (ds Diagnostics) HasErrors() bool {
+	for _, d := range ds {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns an error that describes the diagnostics with SeverityError.
+// If there is no error, it returns nil.
+func (ds Diagnostics) Err() error {
+	var errs []error
+	for _, d := range ds {
+		if d.Severity == SeverityError {
+			errs = append(errs, errors.New(d.String()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type validateCtx struct {
+	tables map[string]*Table
+	diags  Diagnostics
+}
+
+// Validate checks the schema semantically, and reports the problems
+// that MySQL would report on deploying the schema.
+// The schema should be normalized as the parser does.
+func Validate(stmts Stmts) Diagnostics {
+	ctx := &validateCtx{
+		tables: make(map[string]*Table),
+	}
+
+	var tables []*Table
+	for _, stmt := range stmts {
+		table, ok := stmt.(*Table)
+		if !ok {
+			continue
+		}
+		if _, ok := ctx.tables[table.ID()]; ok {
+			ctx.errorf(table.Pos, table, "table %s is defined more than once", table.Name.Quoted())
+			continue
+		}
+		ctx.tables[table.ID()] = table
+		tables = append(tables, table)
+	}
+
+	for _, table := range tables {
+		ctx.validateTable(table)
+		ctx.validateSize(table)
+	}
+
+	if _, err := SortTables(tables); err != nil {
+		var cycle *CycleError
+		if errors.As(err, &cycle) {
+			table := ctx.tables["table#"+strings.ToLower(string(cycle.Tables[0]))]
+			ctx.warnf(table.Pos, table, "%s; some foreign keys are added by ALTER TABLE after the tables are created", strings.TrimPrefix(cycle.Error(), "model: "))
+		}
+	}
+	return ctx.diags
+}
+
+func (ctx *validateCtx) errorf(pos Position, table *Table, format string, args ...any) {
+	ctx.diags = append(ctx.diags, &Diagnostic{
+		Pos:      pos,
+		Severity: SeverityError,
+		Table:    table.Name,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (ctx *validateCtx) warnf(pos Position, table *Table, format string, args ...any) {
+	ctx.diags = append(ctx.diags, &Diagnostic{
+		Pos:      pos,
+		Severity: SeverityWarning,
+		Table:    table.Name,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (ctx *validateCtx) checkIdent(pos Position, table *Table, kind string, ident Ident) {
+	if n := utf8.RuneCountInString(string(ident)); n > MaxIdentLength {
+		ctx.errorf(pos, table, "%s name %s is too long (%d characters, max %d)", kind, ident.Quoted(), n, MaxIdentLength)
+	}
+}
+
+func (ctx *validateCtx) validateTable(table *Table) {
+	ctx.checkIdent(table.Pos, table, "table", table.Name)
+	if table.LikeTable.Valid {
+		return
+	}
+	if len(table.Columns) == 0 {
+		ctx.errorf(table.Pos, table, "a table must have at least 1 column")
+	}
+
+	// columns
+	seen := make(map[string]struct{}, len(table.Columns))
+	var autoIncrements []*TableColumn
+	for _, col := range table.Columns {
+		ctx.checkIdent(col.Pos, table, "column", col.Name)
+		if _, ok := seen[col.ID()]; ok {
+			ctx.errorf(col.Pos, table, "duplicate column name %s", col.Name.Quoted())
+		}
+		seen[col.ID()] = struct{}{}
+		if col.AutoIncrement {
+			autoIncrements = append(autoIncrements, col)
+		}
+	}
+	if len(autoIncrements) > 1 {
+		ctx.errorf(autoIncrements[1].Pos, table, "there can be only one auto column")
+	}
+	for _, col := range autoIncrements {
+		if !isFirstColumnOfKey(table, col) {
+			ctx.errorf(col.Pos, table, "auto column %s must be the first column of a key", col.Name.Quoted())
+		}
+	}
+
+	// indexes
+	var primary *Index
+	indexNames := make(map[string]*Index)
+	constraintNames := make(map[string]*Index)
+	for _, idx := range table.Indexes {
+		if idx.Kind == IndexKindPrimaryKey {
+			if primary != nil {
+				ctx.errorf(idx.Pos, table, "multiple primary key defined")
+			}
+			primary = idx
+		}
+		if idx.Name.Valid {
+			ctx.checkIdent(idx.Pos, table, "index", idx.Name.Ident)
+		}
+		if idx.ConstraintName.Valid {
+			ctx.checkIdent(idx.Pos, table, "constraint", idx.ConstraintName.Ident)
+		}
+
+		// foreign keys and the other indexes have their own namespaces.
+		names := indexNames
+		name := idx.Name
+		if idx.Kind == IndexKindForeignKey {
+			names = constraintNames
+			name = getConstraintName(idx)
+		}
+		if name.Valid {
+			key := strings.ToLower(string(name.Ident))
+			if other, ok := names[key]; ok {
+				// the parser may declare the implicit index of a foreign key, ignore it.
+				if !isImplicitIndex(table, other) && !isImplicitIndex(table, idx) {
+					ctx.errorf(idx.Pos, table, "duplicate key name %s", name.Ident.Quoted())
+				}
+			}
+			names[key] = idx
+		}
+
+		for _, col := range idx.Columns {
+			if _, ok := table.LookupColumn(columnID(col.Name)); !ok {
+				ctx.errorf(idx.Pos, table, "key column %s doesn't exist in table", col.Name.Quoted())
+			}
+		}
+
+		if idx.Kind == IndexKindForeignKey {
+			ctx.validateForeignKey(table, idx)
+		}
+	}
+}
+
+func getConstraintName(idx *Index) MaybeIdent {
+	if idx.ConstraintName.Valid {
+		return idx.ConstraintName
+	}
+	return idx.Name
+}
+
+func columnID(name Ident) string {
+	return "tablecol#" + strings.ToLower(string(name))
+}
+
+// isFirstColumnOfKey returns whether col is the first column of any index of table.
+func isFirstColumnOfKey(table *Table, col *TableColumn) bool {
+	for _, idx := range table.Indexes {
+		switch idx.Kind {
+		case IndexKindPrimaryKey, IndexKindNormal, IndexKindUnique:
+		default:
+			continue
+		}
+		if len(idx.Columns) > 0 && strings.EqualFold(string(idx.Columns[0].Name), string(col.Name)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ctx *validateCtx) validateForeignKey(table *Table, fk *Index) {
+	ref := fk.Reference
+	if ref == nil {
+		ctx.errorf(fk.Pos, table, "foreign key has no reference")
+		return
+	}
+	parent, ok := ctx.tables[ref.TableID()]
+	if !ok {
+		ctx.errorf(fk.Pos, table, "foreign key refers table %s that doesn't exist", ref.TableName.Quoted())
+		return
+	}
+	if len(fk.Columns) != len(ref.Columns) {
+		ctx.errorf(fk.Pos, table, "foreign key has %d columns, but it refers %d columns", len(fk.Columns), len(ref.Columns))
+		return
+	}
+	for i, col := range fk.Columns {
+		child, ok := table.LookupColumn(columnID(col.Name))
+		if !ok {
+			// already reported as a missing key column.
+			continue
+		}
+		refCol, ok := parent.LookupColumn(columnID(ref.Columns[i].Name))
+		if !ok {
+			ctx.errorf(fk.Pos, table, "foreign key refers column %s.%s that doesn't exist", ref.TableName.Quoted(), ref.Columns[i].Name.Quoted())
+			continue
+		}
+		if !compatibleForeignKeyColumns(child, refCol) {
+			ctx.errorf(fk.Pos, table, "column %s and referenced column %s.%s in foreign key are incompatible",
+				child.Name.Quoted(), ref.TableName.Quoted(), refCol.Name.Quoted())
+		}
+	}
+}
+
+// compatibleForeignKeyColumns returns whether the columns can be used in a foreign key.
+// https://dev.mysql.com/doc/refman/8.0/en/create-table-foreign-keys.html#foreign-key-restrictions
+func compatibleForeignKeyColumns(a, b *TableColumn) bool {
+	if a.Type.SynonymType() != b.Type.SynonymType() {
+		return false
+	}
+	if a.Unsigned != b.Unsigned {
+		return false
+	}
+	switch a.Type.SynonymType() {
+	case ColumnTypeDecimal:
+		// the size and the precision must be the same.
+		return a.Length.Equal(b.Length)
+	case ColumnTypeChar, ColumnTypeVarChar, ColumnTypeText, ColumnTypeTinyText, ColumnTypeMediumText, ColumnTypeLongText:
+		// the length doesn't matter, but the character set must be the same.
+		if a.CharacterSet.Valid && b.CharacterSet.Valid {
+			return equalMaybeIdentFold(a.CharacterSet, b.CharacterSet)
+		}
+	}
+	return true
+}
+
+// validateSize checks the limits of the key length and the row size.
+func (ctx *validateCtx) validateSize(table *Table) {
+	if table.LikeTable.Valid {
+		return
+	}
+	size := AnalyzeSize(table)
+	compact := size.RowFormat == "REDUNDANT" || size.RowFormat == "COMPACT"
+	for _, is := range size.Indexes {
+		idx := is.Index
+		for i, n := range is.Parts {
+			col := idx.Columns[i]
+			if n < 0 {
+				ctx.errorf(idx.Pos, table, "BLOB/TEXT column %s used in key specification without a key length", col.Name.Quoted())
+				continue
+			}
+			if compact && n > MaxKeyPartLengthCompact {
+				ctx.errorf(idx.Pos, table, "key part %s is too long (%d bytes, max %d bytes for ROW_FORMAT=%s)", col.Name.Quoted(), n, MaxKeyPartLengthCompact, size.RowFormat)
+			}
+		}
+		if is.Bytes > MaxKeyLength {
+			ctx.errorf(idx.Pos, table, "specified key was too long (%d bytes, max %d bytes)", is.Bytes, MaxKeyLength)
+		}
+	}
+	if size.RowBytes > MaxRowSize {
+		ctx.errorf(table.Pos, table, "row size too large (%d bytes, max %d bytes, not counting BLOBs)", size.RowBytes, MaxRowSize)
+	} else if size.InnoDBRowBytes > MaxInnoDBRowSize {
+		ctx.errorf(table.Pos, table, "row size too large (at least %d bytes, max %d bytes for InnoDB with ROW_FORMAT=%s)", size.InnoDBRowBytes, MaxInnoDBRowSize, size.RowFormat)
+	}
+}
+
+// isImplicitIndex reports whether the index is the one that the parser declares for a foreign key,
+// that is, it is declared at the same position as the foreign key with the constraint name.
+func isImplicitIndex(table *Table, idx *Index) bool {
+	if idx.Kind != IndexKindNormal || !idx.Name.Valid {
+		return false
+	}
+	for _, fk := range table.Indexes {
+		if fk.Kind != IndexKindForeignKey || fk.Pos != idx.Pos || !fk.ConstraintName.Valid {
+			continue
+		}
+		if strings.EqualFold(string(fk.ConstraintName.Ident), string(idx.Name.Ident)) {
+			return true
+		}
+	}
+	return false
+}
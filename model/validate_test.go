@@ -0,0 +1,122 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/schemalex-deploy"
+	"github.com/shogo82148/schemalex-deploy/model"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{
+			name: "valid",
+			sql: "CREATE TABLE `f` ( `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT, PRIMARY KEY (`id`) );\n" +
+				"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `fid` BIGINT UNSIGNED NOT NULL,\n" +
+				"CONSTRAINT `fk` FOREIGN KEY (`fid`) REFERENCES `f` (`id`) );",
+			want: nil,
+		},
+		{
+			name: "index on a missing column",
+			sql:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL,\nINDEX `idx` (`name`) );",
+			want: []string{"error at line 2 column 0 in table `hoge`: key column `name` doesn't exist in table"},
+		},
+		{
+			name: "foreign key to a missing table",
+			sql:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL,\nFOREIGN KEY (`id`) REFERENCES `fuga` (`id`) );",
+			want: []string{"error at line 2 column 0 in table `hoge`: foreign key refers table `fuga` that doesn't exist"},
+		},
+		{
+			name: "foreign key to a missing column",
+			sql: "CREATE TABLE `f` ( `id` INTEGER NOT NULL );\n" +
+				"CREATE TABLE `hoge` ( `id` INTEGER NOT NULL,\nFOREIGN KEY (`id`) REFERENCES `f` (`fid`) );",
+			want: []string{"error at line 3 column 0 in table `hoge`: foreign key refers column `f`.`fid` that doesn't exist"},
+		},
+		{
+			name: "foreign key type mismatch",
+			sql: "CREATE TABLE `f` ( `id` BIGINT UNSIGNED NOT NULL, PRIMARY KEY (`id`) );\n" +
+				"CREATE TABLE `hoge` ( `fid` INTEGER NOT NULL,\nFOREIGN KEY (`fid`) REFERENCES `f` (`id`) );",
+			want: []string{"error at line 3 column 0 in table `hoge`: column `fid` and referenced column `f`.`id` in foreign key are incompatible"},
+		},
+		{
+			name: "auto increment column is not a key",
+			sql:  "CREATE TABLE `hoge` (\n`id` INTEGER NOT NULL AUTO_INCREMENT,\n`name` TEXT,\nINDEX (`name`(10), `id`) );",
+			want: []string{"error at line 2 column 0 in table `hoge`: auto column `id` must be the first column of a key"},
+		},
+		{
+			name: "duplicate index names",
+			sql:  "CREATE TABLE `hoge` ( `a` INTEGER, `b` INTEGER,\nINDEX `idx` (`a`),\nINDEX `IDX` (`b`) );",
+			want: []string{"error at line 3 column 0 in table `hoge`: duplicate key name `IDX`"},
+		},
+		{
+			name: "duplicate identical indexes",
+			sql:  "CREATE TABLE `hoge` ( `a` INTEGER,\nINDEX `idx` (`a`),\nINDEX `idx` (`a`) );",
+			want: []string{"error at line 3 column 0 in table `hoge`: duplicate key name `idx`"},
+		},
+		{
+			name: "explicit index of foreign key",
+			sql: "CREATE TABLE `f` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) );\n" +
+				"CREATE TABLE `hoge` ( `fid` INTEGER NOT NULL,\nCONSTRAINT `fk` FOREIGN KEY (`fid`) REFERENCES `f` (`id`),\nINDEX `fk` (`fid`) );",
+			want: nil,
+		},
+		{
+			name: "too long identifier",
+			sql:  "CREATE TABLE `hoge` (\n`" + strings.Repeat("a", 65) + "` INTEGER );",
+			want: []string{"error at line 2 column 0 in table `hoge`: column name `" + strings.Repeat("a", 65) + "` is too long (65 characters, max 64)"},
+		},
+		{
+			name: "multiple primary keys",
+			sql:  "CREATE TABLE `hoge` (\n`a` INTEGER PRIMARY KEY,\n`b` INTEGER,\nPRIMARY KEY (`b`) );",
+			want: []string{"error at line 4 column 0 in table `hoge`: multiple primary key defined"},
+		},
+		{
+			name: "too long key",
+			sql:  "CREATE TABLE `hoge` ( `a` VARCHAR(512), `b` VARCHAR(512),\nINDEX `idx` (`a`, `b`) );",
+			want: []string{"error at line 2 column 0 in table `hoge`: specified key was too long (4096 bytes, max 3072 bytes)"},
+		},
+		{
+			name: "too long key part in compact row format",
+			sql:  "CREATE TABLE `hoge` ( `a` VARCHAR(255),\nINDEX `idx` (`a`) ) ROW_FORMAT=COMPACT;",
+			want: []string{"error at line 2 column 0 in table `hoge`: key part `a` is too long (1020 bytes, max 767 bytes for ROW_FORMAT=COMPACT)"},
+		},
+		{
+			name: "text key without prefix length",
+			sql:  "CREATE TABLE `hoge` ( `a` TEXT,\nINDEX `idx` (`a`) );",
+			want: []string{"error at line 2 column 0 in table `hoge`: BLOB/TEXT column `a` used in key specification without a key length"},
+		},
+		{
+			name: "too large row",
+			sql:  "CREATE TABLE `hoge` ( `a` VARCHAR(10000), `b` VARCHAR(10000) ) DEFAULT CHARSET=utf8mb4;",
+			want: []string{"error at line 1 column 14 in table `hoge`: row size too large (80005 bytes, max 65535 bytes, not counting BLOBs)"},
+		},
+		{
+			name: "circular references",
+			sql: "CREATE TABLE `a` ( `id` INTEGER NOT NULL, `bid` INTEGER, PRIMARY KEY (`id`), FOREIGN KEY (`bid`) REFERENCES `b` (`id`) );\n" +
+				"CREATE TABLE `b` ( `id` INTEGER NOT NULL, `aid` INTEGER, PRIMARY KEY (`id`), FOREIGN KEY (`aid`) REFERENCES `a` (`id`) );",
+			want: []string{"warning at line 1 column 14 in table `a`: circular foreign key references: `a` -> `b` -> `a`; some foreign keys are added by ALTER TABLE after the tables are created"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := schemalex.New(schemalex.WithPositions(true))
+			stmts, err := p.ParseString(tt.sql)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, d := range model.Validate(stmts) {
+				got = append(got, d.String())
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("mismatch (-want/+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -49,11 +49,36 @@ const (
 )
 
 // Parser is responsible to parse a set of SQL statements
-type Parser struct{}
+type Parser struct {
+	positions bool
+}
+
+// ParserOption is an option for New.
+type ParserOption interface {
+	apply(p *Parser)
+}
+
+type withPositions bool
+
+func (opt withPositions) apply(p *Parser) {
+	p.positions = bool(opt)
+}
+
+// WithPositions specifies whether the parser records the positions of
+// tables, columns and indexes into their Pos fields.
+// It is disabled by default, because the positions make the models
+// that have same definition not deeply equal.
+func WithPositions(b bool) ParserOption {
+	return withPositions(b)
+}
 
 // New creates a new Parser
-func New() *Parser {
-	return &Parser{}
+func New(options ...ParserOption) *Parser {
+	p := &Parser{}
+	for _, opt := range options {
+		opt.apply(p)
+	}
+	return p
 }
 
 type parseCtx struct {
@@ -113,6 +138,7 @@ LOOP:
 		ctx.skipWhiteSpaces()
 		switch t := ctx.peek(); t.Type {
 		case CREATE:
+			idx := ctx.idx
 			stmt, err := p.parseCreate(ctx)
 			if err != nil {
 				if myerrors.IsIgnorable(err) {
@@ -124,6 +150,9 @@ LOOP:
 				}
 				return nil, fmt.Errorf("failed to parse create: %w", err)
 			}
+			if table, ok := stmt.(*model.Table); ok {
+				table.RenamedFrom = renamedFrom(ctx.annotationsBefore(idx))
+			}
 			stmts = append(stmts, stmt)
 		case COMMENT_IDENT:
 			ctx.advance()
@@ -239,6 +268,7 @@ func (p *Parser) parseCreateTable(ctx *parseCtx) (*model.Table, error) {
 	switch t := ctx.next(); t.Type {
 	case IDENT, BACKTICK_IDENT:
 		table = model.NewTable(t.Ident())
+		p.setPosition(&table.Pos, t)
 	default:
 		return nil, newParseError(ctx, t, "expected IDENT or BACKTICK_IDENT")
 	}
@@ -290,7 +320,9 @@ func (p *Parser) parseCreateTable(ctx *parseCtx) (*model.Table, error) {
 func (p *Parser) parseCreateTableFields(ctx *parseCtx, stmt *model.Table) error {
 	for {
 		ctx.skipWhiteSpaces()
-		switch t := ctx.peek(); t.Type {
+		t := ctx.peek()
+		numColumns, numIndexes := len(stmt.Columns), len(stmt.Indexes)
+		switch t.Type {
 		case CONSTRAINT:
 			if err := p.parseTableConstraint(ctx, stmt); err != nil {
 				return err
@@ -323,12 +355,25 @@ func (p *Parser) parseCreateTableFields(ctx *parseCtx, stmt *model.Table) error
 		case CHECK: // TODO
 			return newParseError(ctx, t, "unsupported field: CHECK")
 		case IDENT, BACKTICK_IDENT:
+			idx := ctx.idx
 			if err := p.parseTableColumn(ctx, stmt); err != nil {
 				return err
 			}
+			// the annotation may be written before or after the column definition.
+			ctx.skipWhiteSpaces()
+			annotations := append(ctx.annotationsBefore(idx), ctx.annotationsBefore(ctx.idx)...)
+			for _, col := range stmt.Columns[numColumns:] {
+				col.RenamedFrom = renamedFrom(annotations)
+			}
 		default:
 			return newParseError(ctx, t, "unexpected create table field token: %s", t.Type)
 		}
+		for _, col := range stmt.Columns[numColumns:] {
+			p.setPosition(&col.Pos, t)
+		}
+		for _, idx := range stmt.Indexes[numIndexes:] {
+			p.setPosition(&idx.Pos, t)
+		}
 
 		ctx.skipWhiteSpaces()
 		switch t := ctx.peek(); t.Type {
@@ -343,6 +388,14 @@ func (p *Parser) parseCreateTableFields(ctx *parseCtx, stmt *model.Table) error
 			}
 			return nil
 		case COMMA:
+			// the annotation may be written after the comma on the same line.
+			if annotations := ctx.annotationsAfter(ctx.idx); len(annotations) > 0 {
+				for _, col := range stmt.Columns[numColumns:] {
+					if !col.RenamedFrom.Valid {
+						col.RenamedFrom = renamedFrom(annotations)
+					}
+				}
+			}
 			ctx.advance()
 			// Expecting another table field, keep looping
 		default:
@@ -1460,6 +1513,16 @@ func (p *Parser) parseColumnIndexOptionValue(ctx *parseCtx, index *model.Index,
 	return newParseError(ctx, t, "expected %v", follow)
 }
 
+// setPosition sets the position of t to pos if the parser records positions.
+func (p *Parser) setPosition(pos *model.Position, t *Token) {
+	// p may be nil. e.g. diff.Strings without diff.WithParser.
+	if p == nil || !p.positions {
+		return
+	}
+	pos.Line = t.Line
+	pos.Col = t.Col
+}
+
 // Skips over whitespaces. Once this method returns, you can be
 // certain that next call to ctx.next()/peek() will result in a
 // non-space token
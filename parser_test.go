@@ -276,3 +276,62 @@ func TestParse1(t *testing.T) {
 	}
 
 }
+
+func TestParseRenamedFrom(t *testing.T) {
+	const src = "-- schemalex:renamed-from old_foo\n" +
+		"CREATE TABLE foo (id int);\n" +
+		"# this is not an annotation\n" +
+		"CREATE TABLE bar (id int);\n" +
+		"/* schemalex:renamed-from `old``baz` */ CREATE TABLE baz (id int);\n"
+	p := schemalex.New()
+	stmts, err := p.ParseString(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []model.MaybeIdent{
+		{Ident: "old_foo", Valid: true},
+		{},
+		{Ident: "old`baz", Valid: true},
+	}
+	var got []model.MaybeIdent
+	for _, stmt := range stmts {
+		got = append(got, stmt.(*model.Table).RenamedFrom)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want/+got):\n%s", diff)
+	}
+}
+
+func TestParseColumnRenamedFrom(t *testing.T) {
+	const src = "CREATE TABLE foo (\n" +
+		"/* schemalex:renamed-from old_a */ a int,\n" +
+		"b int /* schemalex:renamed-from old_b */,\n" +
+		"c int -- schemalex:renamed-from old_c\n" +
+		", d int COMMENT 'schemalex:renamed-from old_d',\n" +
+		"e int, -- schemalex:renamed-from old_e\n" +
+		"f int, /* schemalex:renamed-from old_g */ g int\n" +
+		")"
+	p := schemalex.New()
+	stmts, err := p.ParseString(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []model.MaybeIdent{
+		{Ident: "old_a", Valid: true},
+		{Ident: "old_b", Valid: true},
+		{Ident: "old_c", Valid: true},
+		{},
+		{Ident: "old_e", Valid: true},
+		{},
+		{Ident: "old_g", Valid: true},
+	}
+	var got []model.MaybeIdent
+	for _, col := range stmts[0].(*model.Table).Columns {
+		got = append(got, col.RenamedFrom)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want/+got):\n%s", diff)
+	}
+}